@@ -0,0 +1,62 @@
+package behavior
+
+import (
+	"fmt"
+)
+
+// AlwaysAction 忽略子节点的结果，总是成功（AlwaysSucceedNode）或者总是失败（AlwaysFailNode
+type AlwaysAction struct {
+	INod
+	base Node
+
+	succ bool
+}
+
+func (a *AlwaysAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+}
+
+func (a *AlwaysAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *AlwaysAction) getType() string {
+	if a.succ {
+		return SUCCEEDER
+	}
+	return FAILER
+}
+
+func (a *AlwaysAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *AlwaysAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+	return nil
+}
+
+func (a *AlwaysAction) catch(t *Tick, err error) bool {
+	return true
+}
+
+func (a *AlwaysAction) onNext(t *Tick) {
+
+	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
+		a.base.SetFreeze(true)
+		t.blackboard.Append([]INod{a.base.Children()[0]})
+		return
+	}
+
+	if a.succ || !t.raise(a, fmt.Errorf("%v node %v always fail", a.base.ID(), a.base.Type())) {
+		a.base.finish(t)
+	}
+}
+
+func (a *AlwaysAction) onReset() {
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
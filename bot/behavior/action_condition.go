@@ -64,6 +64,7 @@ ext:
 	return err
 }
 
+// onNext 条件不满足时的失败在 tick 中处理，这里只会在条件满足（或者失败没有被处理）时调用
 func (a *ConditionAction) onNext(t *Tick) {
 
 	if (a.base.ChildrenNum() > 0 && !a.base.GetFreeze()) && a.succ {
@@ -71,7 +72,7 @@ func (a *ConditionAction) onNext(t *Tick) {
 		child := a.base.Children()[0]
 		t.blackboard.Append([]INod{child})
 	} else {
-		a.base.parent.onNext(t)
+		a.base.finish(t)
 	}
 
 }
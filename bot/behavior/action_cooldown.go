@@ -0,0 +1,71 @@
+package behavior
+
+import (
+	"fmt"
+	"time"
+)
+
+// CooldownAction 执行子节点后的 cooldown 毫秒内，再次执行到这个节点时直接失败（不执行子节点
+type CooldownAction struct {
+	INod
+	base Node
+
+	cooldown int64
+	lasttime time.Time // 重置节点时不清理，在循环中同样生效
+}
+
+func (a *CooldownAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+	a.cooldown = int64(t.Cooldown)
+}
+
+func (a *CooldownAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *CooldownAction) getType() string {
+	return COOLDOWN
+}
+
+func (a *CooldownAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *CooldownAction) check() error {
+	if a.cooldown < 0 {
+		return fmt.Errorf("%v node %v invalid cooldown %v", a.base.ID(), a.base.Type(), a.cooldown)
+	}
+	return nil
+}
+
+func (a *CooldownAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+	return a.check()
+}
+
+func (a *CooldownAction) onNext(t *Tick) {
+
+	if a.base.GetFreeze() || a.base.ChildrenNum() == 0 {
+		a.base.finish(t)
+		return
+	}
+
+	if !a.lasttime.IsZero() && time.Since(a.lasttime) < time.Millisecond*time.Duration(a.cooldown) {
+		if !t.raise(a, fmt.Errorf("%v node %v cooling down", a.base.ID(), a.base.Type())) {
+			a.base.finish(t)
+		}
+		return
+	}
+
+	a.lasttime = time.Now()
+	a.base.SetFreeze(true)
+	t.blackboard.Append([]INod{a.base.Children()[0]})
+}
+
+func (a *CooldownAction) onReset() {
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
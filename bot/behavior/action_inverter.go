@@ -0,0 +1,67 @@
+package behavior
+
+import (
+	"fmt"
+)
+
+// InverterAction 反转子节点的结果（子节点成功时失败，失败时成功
+type InverterAction struct {
+	INod
+	base Node
+
+	caught bool
+}
+
+func (a *InverterAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+}
+
+func (a *InverterAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *InverterAction) getType() string {
+	return INVERTER
+}
+
+func (a *InverterAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *InverterAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+	return nil
+}
+
+func (a *InverterAction) catch(t *Tick, err error) bool {
+	a.caught = true
+	return true
+}
+
+func (a *InverterAction) onNext(t *Tick) {
+
+	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
+		a.base.SetFreeze(true)
+		t.blackboard.Append([]INod{a.base.Children()[0]})
+		return
+	}
+
+	if a.caught {
+		a.caught = false
+		a.base.finish(t)
+		return
+	}
+
+	if !t.raise(a, fmt.Errorf("%v node %v child succeeded", a.base.ID(), a.base.Type())) {
+		a.base.finish(t)
+	}
+}
+
+func (a *InverterAction) onReset() {
+	a.caught = false
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
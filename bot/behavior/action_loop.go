@@ -32,15 +32,18 @@ func (a *LoopAction) getBase() *Node {
 	return &a.base
 }
 
+func (a *LoopAction) check() error {
+	if a.loop <= 0 {
+		return fmt.Errorf("%v node %v invalid loop %v", a.base.ID(), a.base.Type(), a.loop)
+	}
+	return nil
+}
+
 func (a *LoopAction) onTick(t *Tick) error {
 
 	a.base.onTick(t)
 
-	if a.loop <= 0 {
-		return fmt.Errorf("%v node %v", a.base.Type(), a.base.ID())
-	}
-
-	return nil
+	return a.check()
 }
 
 func (a *LoopAction) onNext(t *Tick) {
@@ -54,7 +57,7 @@ func (a *LoopAction) onNext(t *Tick) {
 
 		t.blackboard.Append([]INod{a.base.Children()[0]})
 	} else {
-		a.base.parent.onNext(t)
+		a.base.finish(t)
 	}
 
 }
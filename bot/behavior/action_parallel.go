@@ -1,12 +1,38 @@
 package behavior
 
+import (
+	"fmt"
+)
+
+// Parallel 节点的完成策略
+const (
+	ParallelAll      = "all"      // 等待所有分支完成，有分支失败时失败（默认
+	ParallelAny      = "any"      // 任意一个分支完成时结束，取消其他的分支，结果与完成的分支一致
+	ParallelSuccess  = "success"  // success 个分支成功时成功并取消其他的分支，不可能达成时失败
+	ParallelFailFast = "failfast" // 所有分支成功时成功，有分支失败时立即失败并取消其他的分支
+)
+
 type ParallelAction struct {
 	INod
 	base Node
+
+	policy  string
+	success int
+
+	threads []int  // 分支所在的线程
+	done    []bool // 分支是否已经完成
+	succNum int
+	failNum int
 }
 
 func (a *ParallelAction) Init(t *Tree, parent INod, mode Mode) {
 	a.base.Init(t, parent, mode)
+
+	a.policy = t.Policy
+	if a.policy == "" {
+		a.policy = ParallelAll
+	}
+	a.success = int(t.Success)
 }
 
 func (a *ParallelAction) AddChild(nod INod) {
@@ -23,34 +49,138 @@ func (a *ParallelAction) getBase() *Node {
 
 func (a *ParallelAction) onTick(t *Tick) error {
 	a.base.onTick(t)
+
+	return a.check()
+}
+
+func (a *ParallelAction) check() error {
+	switch a.policy {
+	case ParallelAll, ParallelAny, ParallelFailFast:
+	case ParallelSuccess:
+		if a.success <= 0 || a.success > a.base.ChildrenNum() {
+			return fmt.Errorf("%v node %v invalid success %v", a.base.ID(), a.base.Type(), a.success)
+		}
+	default:
+		return fmt.Errorf("%v node %v unknow policy %v", a.base.ID(), a.base.Type(), a.policy)
+	}
+
 	return nil
 }
 
+// collect 统计完成的分支（成功的分支在 finish 时，失败的分支在 raise 时更新了状态
+func (a *ParallelAction) collect() {
+	for k, child := range a.base.Children() {
+		if a.done[k] {
+			continue
+		}
+
+		switch child.getBase().Status() {
+		case NSSucc:
+			a.done[k] = true
+			a.succNum++
+		case NSFail, NSErr:
+			a.done[k] = true
+			a.failNum++
+		}
+	}
+}
+
+// catch 分支失败时调用，返回 false 表示 Parallel 节点失败（失败继续向上传递
+func (a *ParallelAction) catch(t *Tick, err error) bool {
+	a.collect()
+
+	switch a.policy {
+	case ParallelAll:
+		return true
+	case ParallelSuccess:
+		remain := a.base.ChildrenNum() - a.succNum - a.failNum
+		return a.succNum+remain >= a.success
+	}
+
+	return false
+}
+
+// cancel 取消还在执行中的分支
+func (a *ParallelAction) cancel(t *Tick) {
+	for k, child := range a.base.Children() {
+		if !a.done[k] {
+			a.done[k] = true
+			t.abort(child)
+			t.blackboard.ThreadRmv(a.threads[k])
+			child.onReset()
+		}
+	}
+}
+
+// release 移除分支所在的线程（Parallel 节点被祖先节点中断时
+func (a *ParallelAction) release(t *Tick) {
+	for _, num := range a.threads {
+		t.blackboard.ThreadRmv(num)
+	}
+	a.threads = a.threads[:0]
+}
+
+func (a *ParallelAction) start(t *Tick) {
+	a.release(t)
+	a.done = make([]bool, a.base.ChildrenNum())
+	a.succNum, a.failNum = 0, 0
+
+	for _, child := range a.base.Children() {
+		t.blackboard.Append([]INod{child})
+
+		newthreadnum := t.blackboard.ThreadCurNum() + 1
+		t.blackboard.ThreadAdd(newthreadnum)
+		a.threads = append(a.threads, newthreadnum)
+
+		child.getBase().threadNumber = newthreadnum
+	}
+}
+
 func (a *ParallelAction) onNext(t *Tick) {
 	if !a.base.GetFreeze() {
 		a.base.SetFreeze(true)
+		a.start(t)
 
-		for _, children := range a.base.Children() {
-			t.blackboard.Append([]INod{children})
+		if a.base.ChildrenNum() == 0 {
+			a.base.finish(t)
+		}
+		return
+	}
 
-			newthreadnum := t.blackboard.ThreadCurNum() + 1
-			t.blackboard.ThreadAdd(newthreadnum)
+	a.collect()
+	finished := a.succNum + a.failNum
 
-			children.getBase().setThread(newthreadnum)
+	switch a.policy {
+	case ParallelAny:
+		// 失败的分支在 catch 中已经向上传递
+		a.cancel(t)
+		a.base.finish(t)
+		return
+	case ParallelSuccess:
+		if a.succNum >= a.success {
+			a.cancel(t)
+			a.base.finish(t)
+			return
 		}
+	}
 
-	} else {
-		a.base.threadNumber++
+	if finished < a.base.ChildrenNum() {
+		return
+	}
 
-		if a.base.threadNumber >= a.base.ChildrenNum() {
-			a.base.parent.onNext(t)
+	if a.failNum > 0 {
+		if !t.raise(a, fmt.Errorf("%v node %v %v branches failed", a.base.ID(), a.base.Type(), a.failNum)) {
+			a.base.finish(t)
 		}
+		return
 	}
+
+	a.base.finish(t)
 }
 
 func (a *ParallelAction) onReset() {
 	a.base.SetFreeze(false)
-	a.base.threadNumber = 0
+	a.succNum, a.failNum = 0, 0
 
 	for _, child := range a.base.Children() {
 		child.onReset()
@@ -0,0 +1,66 @@
+package behavior
+
+import (
+	"fmt"
+)
+
+// ProbabilityAction 以 probability 的概率执行子节点，没有执行子节点时直接成功
+type ProbabilityAction struct {
+	INod
+	base Node
+
+	probability float64
+}
+
+func (a *ProbabilityAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+	a.probability = t.Probability
+}
+
+func (a *ProbabilityAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *ProbabilityAction) getType() string {
+	return PROB
+}
+
+func (a *ProbabilityAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *ProbabilityAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+
+	return a.check()
+}
+
+func (a *ProbabilityAction) check() error {
+	if a.probability < 0 || a.probability > 1 {
+		return fmt.Errorf("%v node %v invalid probability %v", a.base.ID(), a.base.Type(), a.probability)
+	}
+
+	return nil
+}
+
+func (a *ProbabilityAction) onNext(t *Tick) {
+
+	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
+		a.base.SetFreeze(true)
+
+		if t.rand.Float64() < a.probability {
+			t.blackboard.Append([]INod{a.base.Children()[0]})
+			return
+		}
+	}
+
+	a.base.finish(t)
+}
+
+func (a *ProbabilityAction) onReset() {
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
@@ -0,0 +1,101 @@
+package behavior
+
+import (
+	"fmt"
+)
+
+// RandomSelectAction 按照子节点的权重随机选择一个子节点执行，结果与选中的子节点一致
+type RandomSelectAction struct {
+	INod
+	base Node
+
+	weights []int
+}
+
+func (a *RandomSelectAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+
+	a.weights = a.weights[:0]
+	for _, child := range t.Children {
+		weight := 1
+		if child.Weight != nil {
+			weight = int(*child.Weight)
+		}
+		a.weights = append(a.weights, weight)
+	}
+}
+
+func (a *RandomSelectAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *RandomSelectAction) getType() string {
+	return RANDSEL
+}
+
+func (a *RandomSelectAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *RandomSelectAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+
+	return a.check()
+}
+
+func (a *RandomSelectAction) check() error {
+	total := 0
+	for k, weight := range a.weights {
+		if weight < 0 {
+			return fmt.Errorf("%v node %v child %v invalid weight %v", a.base.ID(), a.base.Type(), k, weight)
+		}
+		total += weight
+	}
+
+	if len(a.weights) > 0 && total == 0 {
+		return fmt.Errorf("%v node %v all weights are 0", a.base.ID(), a.base.Type())
+	}
+
+	return nil
+}
+
+// pick 按照权重选择子节点
+func (a *RandomSelectAction) pick(t *Tick) INod {
+	total := 0
+	for _, weight := range a.weights {
+		total += weight
+	}
+
+	if total <= 0 {
+		return a.base.Children()[0]
+	}
+
+	r := t.rand.Intn(total)
+	for k, weight := range a.weights {
+		if r < weight {
+			return a.base.Children()[k]
+		}
+		r -= weight
+	}
+
+	return a.base.Children()[len(a.weights)-1]
+}
+
+func (a *RandomSelectAction) onNext(t *Tick) {
+
+	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
+		a.base.SetFreeze(true)
+		t.blackboard.Append([]INod{a.pick(t)})
+		return
+	}
+
+	a.base.finish(t)
+}
+
+func (a *RandomSelectAction) onReset() {
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
@@ -0,0 +1,93 @@
+package behavior
+
+import (
+	"fmt"
+)
+
+// RepeatUntilAction 重复执行子节点，直到子节点成功（RepeatUntilSuccessNode）或者失败（RepeatUntilFailureNode
+// loop 不为 0 时最多执行 loop 次，仍然没有得到期望的结果时失败
+type RepeatUntilAction struct {
+	INod
+	base Node
+
+	untilSucc bool
+	loop      int64
+	curLoop   int64
+	caught    bool
+}
+
+func (a *RepeatUntilAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+	a.loop = int64(t.Loop)
+}
+
+func (a *RepeatUntilAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *RepeatUntilAction) getType() string {
+	if a.untilSucc {
+		return UNTILSUCC
+	}
+	return UNTILFAIL
+}
+
+func (a *RepeatUntilAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *RepeatUntilAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+	return nil
+}
+
+func (a *RepeatUntilAction) catch(t *Tick, err error) bool {
+	a.caught = true
+	return true
+}
+
+func (a *RepeatUntilAction) onNext(t *Tick) {
+
+	if a.base.ChildrenNum() == 0 {
+		a.base.finish(t)
+		return
+	}
+
+	if a.base.GetFreeze() {
+		// 子节点执行完成，得到期望的结果时结束
+		succ := !a.caught
+		a.caught = false
+
+		if succ == a.untilSucc {
+			a.curLoop = 0
+			a.base.finish(t)
+			return
+		}
+
+		if a.loop > 0 && a.curLoop >= a.loop {
+			a.curLoop = 0
+			if !t.raise(a, fmt.Errorf("%v node %v not satisfied after %v times", a.base.ID(), a.base.Type(), a.loop)) {
+				a.base.finish(t)
+			}
+			return
+		}
+	}
+
+	a.base.SetFreeze(true)
+	a.curLoop++
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+	t.blackboard.Append([]INod{a.base.Children()[0]})
+}
+
+func (a *RepeatUntilAction) onReset() {
+	a.curLoop = 0
+	a.caught = false
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
@@ -0,0 +1,118 @@
+package behavior
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	BackoffFixed       = "fixed"
+	BackoffExponential = "exponential"
+)
+
+// RetryAction 子节点失败时重新执行子节点，最多重试 retry 次（间隔 interval 毫秒，exponential 时每次翻倍
+type RetryAction struct {
+	INod
+	base Node
+
+	retry    int
+	backoff  string
+	interval int64
+
+	retried int
+	retryAt time.Time // 不为空表示正在等待重试
+}
+
+func (a *RetryAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+
+	a.retry = int(t.Retry)
+	a.backoff = t.Backoff
+	a.interval = int64(t.Interval)
+}
+
+func (a *RetryAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *RetryAction) getType() string {
+	return RETRY
+}
+
+func (a *RetryAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *RetryAction) due() time.Time {
+	return a.retryAt
+}
+
+func (a *RetryAction) check() error {
+	if a.retry < 0 {
+		return fmt.Errorf("%v node %v invalid retry %v", a.base.ID(), a.base.Type(), a.retry)
+	}
+	if a.interval < 0 {
+		return fmt.Errorf("%v node %v invalid interval %v", a.base.ID(), a.base.Type(), a.interval)
+	}
+	return nil
+}
+
+func (a *RetryAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+	return a.check()
+}
+
+// catch 子节点失败时调用，还有重试次数时返回 true（失败被处理，不再向上传递
+func (a *RetryAction) catch(t *Tick, err error) bool {
+	if a.retried >= a.retry {
+		return false
+	}
+
+	wait := time.Millisecond * time.Duration(a.interval)
+	if a.backoff == BackoffExponential {
+		wait = wait << a.retried
+	}
+
+	a.retried++
+	a.retryAt = time.Now().Add(wait)
+	t.stat(a).Retry++
+
+	return true
+}
+
+func (a *RetryAction) onNext(t *Tick) {
+
+	if !a.retryAt.IsZero() {
+		if time.Now().Before(a.retryAt) {
+			t.blackboard.Append([]INod{a})
+			return
+		}
+
+		// 重新执行子节点
+		a.retryAt = time.Time{}
+		for _, child := range a.base.Children() {
+			child.onReset()
+		}
+		t.blackboard.Append([]INod{a.base.Children()[0]})
+		return
+	}
+
+	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
+		a.base.SetFreeze(true)
+		t.blackboard.Append([]INod{a.base.Children()[0]})
+	} else {
+		a.retried = 0
+		a.base.finish(t)
+	}
+
+}
+
+func (a *RetryAction) onReset() {
+	a.retried = 0
+	a.retryAt = time.Time{}
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
@@ -3,6 +3,8 @@ package behavior
 type RootAction struct {
 	INod
 	base Node
+
+	failed bool
 }
 
 func (a *RootAction) Init(t *Tree, parent INod, mode Mode) {
@@ -28,11 +30,22 @@ func (a *RootAction) onTick(t *Tick) error {
 	return nil
 }
 
+// catch 没有被其他节点处理的失败，行为树以失败结束
+func (a *RootAction) catch(t *Tick, err error) bool {
+	a.failed = true
+	return true
+}
+
 func (a *RootAction) onNext(t *Tick) {
 	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
 		a.base.SetFreeze(true)
 		t.blackboard.Append([]INod{a.base.Children()[0]})
 	} else {
+		a.base.status = NSSucc
+		if a.failed {
+			a.base.status = NSFail
+			t.failed = true
+		}
 		t.blackboard.End()
 	}
 }
@@ -66,7 +66,7 @@ func (a *ScriptAction) onNext(t *Tick) {
 		a.base.SetFreeze(true)
 		t.blackboard.Append([]INod{a.base.Children()[0]})
 	} else {
-		a.base.parent.onNext(t)
+		a.base.finish(t)
 	}
 
 }
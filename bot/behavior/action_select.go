@@ -1,10 +1,12 @@
 package behavior
 
+// SelectAction 依次执行子节点，直到有一个子节点成功（所有子节点都失败时失败
 type SelectAction struct {
 	INod
 	base Node
 
-	step int
+	step   int
+	caught bool
 }
 
 func (a *SelectAction) Init(t *Tree, parent INod, mode Mode) {
@@ -26,36 +28,34 @@ func (a *SelectAction) getBase() *Node {
 func (a *SelectAction) onTick(t *Tick) error {
 	a.base.onTick(t)
 
-	if a.base.ChildrenNum() <= 0 {
-		goto ext
-	}
+	return nil
+}
 
-	if a.step != 0 {
-		self := a.base.Children()[a.step-1].(*ConditionAction)
-		if self.succ {
-			a.step = a.base.ChildrenNum()
-			goto ext
-		}
+// catch 子节点失败时尝试下一个子节点，没有剩余的子节点时失败
+func (a *SelectAction) catch(t *Tick, err error) bool {
+	if a.step >= a.base.ChildrenNum() {
+		return false
 	}
 
-ext:
-
-	return nil
+	a.caught = true
+	return true
 }
 
 func (a *SelectAction) onNext(t *Tick) {
 
-	if a.step < a.base.ChildrenNum() {
+	if (a.step == 0 || a.caught) && a.step < a.base.ChildrenNum() {
+		a.caught = false
 		a.step++
 		t.blackboard.Append([]INod{a.base.Children()[a.step-1]})
 	} else {
-		a.base.parent.onNext(t)
+		a.base.finish(t)
 	}
 
 }
 
 func (a *SelectAction) onReset() {
 	a.step = 0
+	a.caught = false
 
 	for _, child := range a.base.Children() {
 		child.onReset()
@@ -36,7 +36,7 @@ func (a *SequenceAction) onNext(t *Tick) {
 		t.blackboard.Append([]INod{a.base.Children()[a.step-1]})
 
 	} else {
-		a.base.parent.onNext(t)
+		a.base.finish(t)
 	}
 
 }
@@ -0,0 +1,86 @@
+package behavior
+
+import (
+	"fmt"
+	"strconv"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// SubtreeAction 执行引用的行为树（在 Load 时链接到子节点，依次执行子节点
+type SubtreeAction struct {
+	INod
+	base Node
+
+	params []Param
+	step   int
+}
+
+func (a *SubtreeAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+	a.params = t.Params
+}
+
+func (a *SubtreeAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *SubtreeAction) getType() string {
+	return SUBTREE
+}
+
+func (a *SubtreeAction) getBase() *Node {
+	return &a.base
+}
+
+// paramValue 参数的值可以是数字、布尔值或者字符串
+func paramValue(val string) lua.LValue {
+	if n, err := strconv.ParseFloat(val, 64); err == nil {
+		return lua.LNumber(n)
+	}
+	switch val {
+	case "true":
+		return lua.LTrue
+	case "false":
+		return lua.LFalse
+	}
+	return lua.LString(val)
+}
+
+func (a *SubtreeAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+
+	if len(a.params) == 0 {
+		return nil
+	}
+
+	meta, ok := t.bs.L.GetGlobal("meta").(*lua.LTable)
+	if !ok {
+		return fmt.Errorf("%v node %v can't find meta", a.base.ID(), a.base.Type())
+	}
+
+	for _, p := range a.params {
+		meta.RawSetString(p.Key, paramValue(p.Value))
+	}
+
+	return nil
+}
+
+func (a *SubtreeAction) onNext(t *Tick) {
+
+	if a.step < a.base.ChildrenNum() {
+		a.step++
+		t.blackboard.Append([]INod{a.base.Children()[a.step-1]})
+	} else {
+		a.base.finish(t)
+	}
+
+}
+
+func (a *SubtreeAction) onReset() {
+	a.step = 0
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
@@ -0,0 +1,102 @@
+package behavior
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutAction 子节点在 timeout 毫秒内没有执行完成时，中断子节点并返回失败
+// 子节点执行脚本时使用 ctx 作为 lua 的 context（脚本中的 http 请求等会在超时后被取消
+type TimeoutAction struct {
+	INod
+	base Node
+
+	timeout int64
+	endtime time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// timeoutError 超时节点产生的错误（owner 为超时的 TimeoutAction
+type timeoutError struct {
+	owner *TimeoutAction
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%v node %v timeout after %vms", e.owner.base.ID(), e.owner.base.Type(), e.owner.timeout)
+}
+
+func (a *TimeoutAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+	a.timeout = int64(t.Timeout)
+}
+
+func (a *TimeoutAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *TimeoutAction) getType() string {
+	return TIMEOUT
+}
+
+func (a *TimeoutAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *TimeoutAction) check() error {
+	if a.timeout <= 0 {
+		return fmt.Errorf("%v node %v invalid timeout %v", a.base.ID(), a.base.Type(), a.timeout)
+	}
+	return nil
+}
+
+func (a *TimeoutAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+
+	if err := a.check(); err != nil {
+		return err
+	}
+
+	if a.ctx == nil {
+		parent := context.Background()
+		if scope := t.timeoutScope(a); scope != nil {
+			parent = scope.ctx
+		}
+
+		a.endtime = time.Now().Add(time.Millisecond * time.Duration(a.timeout))
+		a.ctx, a.cancel = context.WithDeadline(parent, a.endtime)
+	}
+
+	return nil
+}
+
+func (a *TimeoutAction) stop() {
+	if a.cancel != nil {
+		a.cancel()
+	}
+	a.ctx, a.cancel = nil, nil
+	a.endtime = time.Time{}
+}
+
+func (a *TimeoutAction) onNext(t *Tick) {
+
+	if a.base.ChildrenNum() > 0 && !a.base.GetFreeze() {
+		a.base.SetFreeze(true)
+		t.blackboard.Append([]INod{a.base.Children()[0]})
+	} else {
+		a.stop()
+		a.base.finish(t)
+	}
+
+}
+
+func (a *TimeoutAction) onReset() {
+	a.stop()
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
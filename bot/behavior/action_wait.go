@@ -1,20 +1,44 @@
 package behavior
 
 import (
+	"fmt"
 	"time"
 )
 
+// WaitNode 等待时间的分布
+const (
+	WaitFixed       = "fixed"       // 固定等待 wait 毫秒（默认
+	WaitUniform     = "uniform"     // 在 [min, max] 之间均匀分布
+	WaitNormal      = "normal"      // 均值为 wait，标准差为 stddev 的正态分布
+	WaitExponential = "exponential" // 均值为 wait 的指数分布
+	WaitPoisson     = "poisson"     // 泊松节奏，相邻两次结束等待的间隔服从均值为 wait 的指数分布（不受节点执行时间的影响
+)
+
 type WaitAction struct {
 	INod
 	base Node
 
-	wait    int64
-	endtime int64
+	distribution string
+	wait         int64
+	min          int64
+	max          int64
+	stddev       int64
+
+	endtime  int64
+	pacetime int64 // poisson 下一次的节拍时间（重置时保留
 }
 
 func (a *WaitAction) Init(t *Tree, parent INod, mode Mode) {
 	a.base.Init(t, parent, mode)
 	a.wait = int64(t.Wait)
+
+	a.distribution = t.Distribution
+	if a.distribution == "" {
+		a.distribution = WaitFixed
+	}
+	a.min = int64(t.Min)
+	a.max = int64(t.Max)
+	a.stddev = int64(t.Stddev)
 }
 
 func (a *WaitAction) AddChild(nod INod) {
@@ -29,16 +53,76 @@ func (a *WaitAction) getBase() *Node {
 	return &a.base
 }
 
+func (a *WaitAction) check() error {
+	if a.wait < 0 || a.min < 0 || a.max < 0 || a.stddev < 0 {
+		return fmt.Errorf("%v node %v negative wait time", a.base.ID(), a.base.Type())
+	}
+
+	switch a.distribution {
+	case WaitFixed, WaitNormal, WaitExponential, WaitPoisson:
+	case WaitUniform:
+		if a.min > a.max {
+			return fmt.Errorf("%v node %v invalid uniform(%v, %v)", a.base.ID(), a.base.Type(), a.min, a.max)
+		}
+	default:
+		return fmt.Errorf("%v node %v unknow distribution %v", a.base.ID(), a.base.Type(), a.distribution)
+	}
+
+	return nil
+}
+
+// duration 按照分布获取本次的等待时间（毫秒，已经乘上了 think time 倍率
+func (a *WaitAction) duration(t *Tick, now int64) int64 {
+	var d float64
+
+	switch a.distribution {
+	case WaitUniform:
+		d = float64(a.min) + t.rand.Float64()*float64(a.max-a.min)
+	case WaitNormal:
+		d = float64(a.wait) + t.rand.NormFloat64()*float64(a.stddev)
+	case WaitExponential:
+		d = t.rand.ExpFloat64() * float64(a.wait)
+	case WaitPoisson:
+		if a.pacetime == 0 || a.pacetime < now {
+			// 第一次执行，或者节点的执行时间超过了节拍（不补偿落后的节拍
+			a.pacetime = now
+		}
+		a.pacetime += int64(t.rand.ExpFloat64() * float64(a.wait) * t.thinktime)
+		return a.pacetime - now
+	default:
+		d = float64(a.wait)
+	}
+
+	if d < 0 {
+		d = 0
+	}
+
+	return int64(d * t.thinktime)
+}
+
 func (a *WaitAction) onTick(t *Tick) error {
 	a.base.onTick(t)
 
+	err := a.check()
+	if err != nil {
+		return err
+	}
+
 	if a.endtime == 0 {
-		a.endtime = time.Now().UnixNano()/1000000 + int64(a.wait)
+		now := time.Now().UnixNano() / 1000000
+		a.endtime = now + a.duration(t, now)
 	}
 
 	return nil
 }
 
+func (a *WaitAction) due() time.Time {
+	if a.endtime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, a.endtime*int64(time.Millisecond))
+}
+
 func (a *WaitAction) onNext(t *Tick) {
 
 	var currTime int64 = time.Now().UnixNano() / 1000000
@@ -49,7 +133,7 @@ func (a *WaitAction) onNext(t *Tick) {
 			a.base.SetFreeze(true)
 			t.blackboard.Append([]INod{a.base.Children()[0]})
 		} else {
-			a.base.parent.onNext(t)
+			a.base.finish(t)
 		}
 
 	} else {
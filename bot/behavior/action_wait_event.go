@@ -0,0 +1,125 @@
+package behavior
+
+import (
+	"fmt"
+	"time"
+)
+
+// WaitForEventAction 等待事件触发后执行子节点（没有子节点时直接成功），timeout 毫秒内没有触发时失败
+// 事件由模块（tcp、shared.watch）或者脚本（event.signal、event.after）触发
+type WaitForEventAction struct {
+	INod
+	base Node
+
+	event   string
+	timeout int64
+
+	waiting  bool
+	fired    bool
+	deadline time.Time
+}
+
+func (a *WaitForEventAction) Init(t *Tree, parent INod, mode Mode) {
+	a.base.Init(t, parent, mode)
+	a.event = t.Event
+	a.timeout = int64(t.Timeout)
+}
+
+func (a *WaitForEventAction) AddChild(nod INod) {
+	a.base.AddChild(nod)
+}
+
+func (a *WaitForEventAction) getType() string {
+	return WAITEVENT
+}
+
+func (a *WaitForEventAction) getBase() *Node {
+	return &a.base
+}
+
+func (a *WaitForEventAction) check() error {
+	if a.event == "" {
+		return fmt.Errorf("%v node %v without event", a.base.ID(), a.base.Type())
+	}
+	if a.timeout < 0 {
+		return fmt.Errorf("%v node %v invalid timeout %v", a.base.ID(), a.base.Type(), a.timeout)
+	}
+	return nil
+}
+
+func (a *WaitForEventAction) onTick(t *Tick) error {
+	a.base.onTick(t)
+
+	err := a.check()
+	if err != nil {
+		return err
+	}
+
+	if !a.waiting {
+		a.waiting = true
+		if a.timeout > 0 {
+			a.deadline = time.Now().Add(time.Millisecond * time.Duration(a.timeout))
+		}
+	}
+
+	if t.bs.Events.Take(a.event) {
+		a.fired = true
+	}
+
+	return nil
+}
+
+// due 等待中的节点在截止时间之前只会被事件唤醒
+func (a *WaitForEventAction) due() time.Time {
+	if !a.waiting || a.fired {
+		return time.Time{}
+	}
+	if a.deadline.IsZero() {
+		return time.Now().Add(time.Hour)
+	}
+	return a.deadline
+}
+
+func (a *WaitForEventAction) onNext(t *Tick) {
+
+	if a.base.GetFreeze() {
+		a.base.finish(t)
+		return
+	}
+
+	if !a.fired {
+		if !a.deadline.IsZero() && !time.Now().Before(a.deadline) {
+			a.reset()
+			if !t.raise(a, fmt.Errorf("%v node %v wait event %v timeout after %vms", a.base.ID(), a.base.Type(), a.event, a.timeout)) {
+				a.base.finish(t)
+			}
+			return
+		}
+
+		t.blackboard.Append([]INod{a})
+		return
+	}
+
+	a.reset()
+	if a.base.ChildrenNum() > 0 {
+		a.base.SetFreeze(true)
+		t.blackboard.Append([]INod{a.base.Children()[0]})
+	} else {
+		a.base.finish(t)
+	}
+}
+
+func (a *WaitForEventAction) reset() {
+	a.waiting = false
+	a.fired = false
+	a.deadline = time.Time{}
+}
+
+func (a *WaitForEventAction) onReset() {
+	a.reset()
+	a.base.SetFreeze(false)
+
+	for _, child := range a.base.Children() {
+		child.onReset()
+	}
+}
@@ -2,6 +2,8 @@ package behavior
 
 import (
 	"encoding/xml"
+	"fmt"
+	"strings"
 )
 
 type Mode int
@@ -16,17 +18,63 @@ type Tree struct {
 	ID string `xml:"id"`
 	Ty string `xml:"ty"`
 
-	Wait int32 `xml:"wait"`
+	Wait         int32  `xml:"wait"`         // 等待节点的等待时间（毫秒，分布为 normal / exponential / poisson 时为均值
+	Distribution string `xml:"distribution"` // 等待时间的分布 fixed / uniform / normal / exponential / poisson
+	Min          int32  `xml:"min"`          // uniform 分布的最小等待时间（毫秒
+	Max          int32  `xml:"max"`          // uniform 分布的最大等待时间（毫秒
+	Stddev       int32  `xml:"stddev"`       // normal 分布的标准差（毫秒
 
 	Loop int32  `xml:"loop"` // 用于记录循环节点的循环x次数
 	Code string `xml:"code"`
 
+	Timeout  int32  `xml:"timeout"`  // 超时节点、事件等待节点的超时时间（毫秒
+	Retry    int32  `xml:"retry"`    // 重试节点的最大重试次数
+	Backoff  string `xml:"backoff"`  // 重试的间隔策略 fixed / exponential
+	Interval int32  `xml:"interval"` // 重试的间隔（毫秒，exponential 时为第一次的间隔
+	Cooldown int32  `xml:"cooldown"` // 冷却节点的冷却时间（毫秒
+
+	Policy  string `xml:"policy"`  // 并行节点的完成策略 all / any / success / failfast
+	Success int32  `xml:"success"` // success 策略下需要成功的分支数量
+
+	Weight      *int32  `xml:"weight"`      // 在随机选择节点中被选中的权重（没有设置时为 1，为 0 时不会被选中
+	Probability float64 `xml:"probability"` // 概率节点执行子节点的概率 0 ~ 1
+
+	Event string `xml:"event"` // 事件等待节点等待的事件
+
+	Ref      string  `xml:"ref"`      // 子树节点引用的行为树名
+	Params   []Param `xml:"params"`   // 子树节点的参数，执行子树前写入 meta
+	Expanded bool    `xml:"expanded"` // 子树节点已经链接了引用的行为树（Expand 的结果，Load 时不再获取
+	Origin   string  `xml:"origin"`   // 子树中的节点对应的编辑器中的子树节点 id（问题和统计记录在这个节点上
+
+	HTTP *RawXML `xml:"http"` // 只在根节点生效，覆盖全局的 http 配置（由 bot 解析
+
 	root INod
 	mode Mode
 
 	Children []*Tree `xml:"children"`
 }
 
+// RawXML 节点中原样保存的 xml 内容
+type RawXML struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+// Param 子树节点的参数
+type Param struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+// SubtreeLoader 通过名字获取子树节点引用的行为树文件
+type SubtreeLoader func(name string) ([]byte, error)
+
+var subtreeLoader SubtreeLoader
+
+// SetSubtreeLoader 设置获取子树的方法（一般为从数据库中获取
+func SetSubtreeLoader(loader SubtreeLoader) {
+	subtreeLoader = loader
+}
+
 func (t *Tree) GetRoot() INod {
 	return t.root
 }
@@ -35,6 +83,14 @@ func (t *Tree) GetMode() Mode {
 	return t.mode
 }
 
+// HttpOptions 行为树根节点中的 http 配置（没有设置时返回 false
+func (t *Tree) HttpOptions() ([]byte, bool) {
+	if t.HTTP == nil {
+		return nil, false
+	}
+	return t.HTTP.Inner, true
+}
+
 func (t *Tree) link(self INod, parent INod, mode Mode) {
 
 	self.Init(t, parent, mode)
@@ -47,6 +103,86 @@ func (t *Tree) link(self INod, parent INod, mode Mode) {
 
 }
 
+// expand 将子树节点引用的行为树链接到子树节点下，refs 为正在展开的行为树（用于检查循环引用
+func (t *Tree) expand(refs []string) error {
+
+	if t.Ty == SUBTREE && !t.Expanded {
+		if t.Ref == "" {
+			return t.problem("without ref")
+		}
+
+		for _, ref := range refs {
+			if ref == t.Ref {
+				return t.problem("cyclic reference %v", strings.Join(append(refs, t.Ref), " -> "))
+			}
+		}
+
+		if subtreeLoader == nil {
+			return t.problem("can't load subtree %v", t.Ref)
+		}
+
+		f, err := subtreeLoader(t.Ref)
+		if err != nil {
+			return t.problem("load subtree %v err %v", t.Ref, err)
+		}
+
+		sub := &Tree{}
+		err = xml.Unmarshal(f, sub)
+		if err != nil {
+			return t.problem("parse subtree %v err %v", t.Ref, err)
+		}
+
+		origin := t.Origin
+		if origin == "" {
+			origin = t.ID
+		}
+
+		// 同一个子树可能被引用多次，节点 id 加上子树节点的 id 作为前缀
+		for _, child := range sub.Children {
+			child.prefix(t.ID+"/", origin)
+		}
+
+		t.Children = sub.Children
+		t.Expanded = true
+		refs = append(refs[:len(refs):len(refs)], t.Ref)
+	}
+
+	for _, child := range t.Children {
+		err := child.expand(refs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Tree) prefix(p string, origin string) {
+	t.ID = p + t.ID
+	t.Origin = origin
+	for _, child := range t.Children {
+		child.prefix(p, origin)
+	}
+}
+
+// Expand 获取行为树中引用的子树，返回展开后的行为树文件
+// 同一个 batch 中的 bot 使用展开后的文件 Load，不会再获取子树（执行期间子树被修改也不会影响这个 batch
+func Expand(f []byte) ([]byte, error) {
+
+	tree := &Tree{}
+	err := xml.Unmarshal(f, tree)
+	if err != nil {
+		return nil, fmt.Errorf("parse behavior err %w", err)
+	}
+
+	err = tree.expand(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return xml.Marshal(tree)
+}
+
 func Load(f []byte, mode Mode) (*Tree, error) {
 
 	tree := &Tree{
@@ -55,7 +191,12 @@ func Load(f []byte, mode Mode) (*Tree, error) {
 
 	err := xml.Unmarshal([]byte(f), &tree)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("parse behavior err %w", err)
+	}
+
+	err = tree.expand(nil)
+	if err != nil {
+		return nil, err
 	}
 
 	tree.root = NewNode(tree.Ty).(INod)
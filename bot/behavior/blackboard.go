@@ -25,6 +25,9 @@ func (b *Blackboard) GetOpenNods() []INod {
 }
 
 func (b *Blackboard) Append(nods []INod) {
+	for _, n := range nods {
+		n.getBase().status = NSRunning
+	}
 	b.Nods = append(b.Nods, nods...)
 }
 
@@ -37,7 +40,12 @@ func (b *Blackboard) ThreadAdd(num int) {
 }
 
 func (b *Blackboard) ThreadRmv(num int) {
-
+	for k, v := range b.Threadlst {
+		if v.Number == num {
+			b.Threadlst = append(b.Threadlst[:k], b.Threadlst[k+1:]...)
+			return
+		}
+	}
 }
 
 func (b *Blackboard) HaveErr() bool {
@@ -57,6 +65,16 @@ func (b *Blackboard) ThreadFillInfo(info ThreadInfo) {
 	}
 }
 
+// threadErr 记录线程中的错误（已经有错误时保留之前的错误
+func (b *Blackboard) threadErr(num int, nod string, msg string) {
+	for k, v := range b.Threadlst {
+		if v.Number == num && v.ErrMsg == "" {
+			b.Threadlst[k].CurNod = nod
+			b.Threadlst[k].ErrMsg = msg
+		}
+	}
+}
+
 func (b *Blackboard) ThreadInfoReset() {
 	for k := range b.Threadlst {
 		b.Threadlst[k].reset()
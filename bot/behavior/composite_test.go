@@ -0,0 +1,734 @@
+package behavior
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequenceStopOnFailure(t *testing.T) {
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") return state.Error, "b fail" end</code>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("c") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "ab")
+	assert.Equal(t, r.state, Error)
+	assert.Equal(t, r.tree.GetRoot().getBase().Status(), NSFail)
+	assert.Equal(t, r.tree.GetRoot().getBase().Children()[0].getBase().Status(), NSFail)
+
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") return state.Succ, {} end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "ab")
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.tree.GetRoot().getBase().Status(), NSSucc)
+}
+
+func TestSelectorUntilSuccess(t *testing.T) {
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>sel</id>
+    <ty>SelectorNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") return state.Error, "a fail" end</code>
+    </children>
+    <children>
+      <id>cond</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return false end</code>
+      <children>
+        <id>skip</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("skip") end</code>
+      </children>
+    </children>
+    <children>
+      <id>cond2</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return true end</code>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("c") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "ab")
+	assert.Equal(t, r.state, Exit)
+
+	// 条件满足，但子节点失败时继续尝试下一个子节点
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>sel</id>
+    <ty>SelectorNode</ty>
+    <children>
+      <id>cond</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return true end</code>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") error("runtime err") end</code>
+      </children>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "ab")
+	assert.Equal(t, r.state, Exit)
+
+	// 所有子节点都失败
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>sel</id>
+    <ty>SelectorNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") return state.Error, "a fail" end</code>
+    </children>
+    <children>
+      <id>cond</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return false end</code>
+      <children>
+        <id>skip</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("skip") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "a")
+	assert.Equal(t, r.state, Error)
+}
+
+func TestConditionFailure(t *testing.T) {
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>cond</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return false end</code>
+      <children>
+        <id>skip</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("skip") end</code>
+      </children>
+    </children>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "")
+	assert.Equal(t, r.state, Error)
+}
+
+func TestParallelWaitAll(t *testing.T) {
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>all</policy>
+    <children>
+      <id>s1</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") end</code>
+      </children>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("c") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "cab")
+	assert.Equal(t, r.state, Exit)
+
+	// 失败的分支不影响其他分支的执行
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>all</policy>
+    <children>
+      <id>s1</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") return state.Error, "a fail" end</code>
+      </children>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+    </children>
+    <children>
+      <id>s2</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>c</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("c") end</code>
+      </children>
+      <children>
+        <id>d</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("d") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "acd")
+	assert.Equal(t, r.state, Error)
+	assert.Equal(t, r.tree.GetRoot().getBase().Children()[0].getBase().Status(), NSFail)
+}
+
+func TestParallelWaitAny(t *testing.T) {
+	// h 一直执行，只能通过取消结束
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>any</policy>
+    <children>
+      <id>h_loop</id>
+      <ty>LoopNode</ty>
+      <loop>0</loop>
+      <children>
+        <id>h</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("h") end</code>
+      </children>
+    </children>
+    <children>
+      <id>main</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") end</code>
+      </children>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+      <children>
+        <id>c</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("c") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "hahbhc")
+	assert.Equal(t, r.state, Exit)
+
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>any</policy>
+    <children>
+      <id>h_loop</id>
+      <ty>LoopNode</ty>
+      <loop>0</loop>
+      <children>
+        <id>h</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("h") end</code>
+      </children>
+    </children>
+    <children>
+      <id>main</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") return state.Error, "a fail" end</code>
+      </children>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "ha")
+	assert.Equal(t, r.state, Error)
+}
+
+func TestParallelSuccessN(t *testing.T) {
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>success</policy>
+    <success>2</success>
+    <children>
+      <id>h_loop</id>
+      <ty>LoopNode</ty>
+      <loop>0</loop>
+      <children>
+        <id>h</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("h") end</code>
+      </children>
+    </children>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>s</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") return state.Error, "b fail" end</code>
+      </children>
+      <children>
+        <id>c</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("c") end</code>
+      </children>
+    </children>
+    <children>
+      <id>s2</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>d</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("d") end</code>
+      </children>
+      <children>
+        <id>e</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("e") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "ahbdhe")
+	assert.Equal(t, r.state, Exit)
+
+	// 失败的分支过多，不可能达到成功的数量
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>success</policy>
+    <success>2</success>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") return state.Error, "a fail" end</code>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("c") return state.Error, "c fail" end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "abc")
+	assert.Equal(t, r.state, Error)
+
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>success</policy>
+    <success>3</success>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.state, Error)
+}
+
+func TestParallelFailFast(t *testing.T) {
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>failfast</policy>
+    <children>
+      <id>h_loop</id>
+      <ty>LoopNode</ty>
+      <loop>0</loop>
+      <children>
+        <id>h</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("h") end</code>
+      </children>
+    </children>
+    <children>
+      <id>s</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") end</code>
+      </children>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") return state.Error, "b fail" end</code>
+      </children>
+      <children>
+        <id>c</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("c") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "hahb")
+	assert.Equal(t, r.state, Error)
+
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>failfast</policy>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>s</id>
+      <ty>SequenceNode</ty>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+      <children>
+        <id>c</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("c") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.order(), "abc")
+	assert.Equal(t, r.state, Exit)
+}
+
+func threadNumbers(r *treeRun) []int {
+	nums := []int{}
+	for _, v := range r.tick.blackboard.Threadlst {
+		nums = append(nums, v.Number)
+	}
+	return nums
+}
+
+func TestParallelReleaseThreads(t *testing.T) {
+	// 被取消的 h 分支的线程被移除
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>parallel</id>
+    <ty>ParallelNode</ty>
+    <policy>any</policy>
+    <children>
+      <id>h_loop</id>
+      <ty>LoopNode</ty>
+      <loop>0</loop>
+      <children>
+        <id>h</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("h") end</code>
+      </children>
+    </children>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, threadNumbers(r), []int{1, 3})
+
+	// Parallel 节点被外层的超时节点中断时，所有分支的线程被移除
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>recover</id>
+      <ty>AlwaysSucceedNode</ty>
+      <children>
+        <id>timeout</id>
+        <ty>TimeoutNode</ty>
+        <timeout>10</timeout>
+        <children>
+          <id>parallel</id>
+          <ty>ParallelNode</ty>
+          <policy>all</policy>
+          <children>
+            <id>h_loop</id>
+            <ty>LoopNode</ty>
+            <loop>0</loop>
+            <children>
+              <id>h</id>
+              <ty>WaitNode</ty>
+              <wait>1</wait>
+            </children>
+          </children>
+          <children>
+            <id>g_loop</id>
+            <ty>LoopNode</ty>
+            <loop>0</loop>
+            <children>
+              <id>g</id>
+              <ty>WaitNode</ty>
+              <wait>1</wait>
+            </children>
+          </children>
+        </children>
+      </children>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+  </children>
+</behavior>`, withSleep())
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "b")
+	assert.Equal(t, threadNumbers(r), []int{1})
+}
+
+func TestRandomSelector(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>loop</id>
+    <ty>LoopNode</ty>
+    <loop>1000</loop>
+    <children>
+      <id>random</id>
+      <ty>RandomSelectorNode</ty>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <weight>60</weight>
+        <code>function execute() mark("a") end</code>
+      </children>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <weight>30</weight>
+        <code>function execute() mark("b") end</code>
+      </children>
+      <children>
+        <id>c</id>
+        <ty>ActionNode</ty>
+        <weight>10</weight>
+        <code>function execute() mark("c") end</code>
+      </children>
+      <children>
+        <id>d</id>
+        <ty>ActionNode</ty>
+        <weight>0</weight>
+        <code>function execute() mark("d") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+	r := runTree(t, tree, withSeed(42))
+	order := r.order()
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, len(order), 1000)
+
+	cnt := map[rune]int{}
+	for _, c := range order {
+		cnt[c]++
+	}
+	assert.InDelta(t, cnt['a'], 600, 60)
+	assert.InDelta(t, cnt['b'], 300, 60)
+	assert.InDelta(t, cnt['c'], 100, 40)
+	assert.Equal(t, cnt['d'], 0) // 权重为 0 的子节点不会被选中
+
+	// 相同的种子得到相同的执行路径
+	assert.Equal(t, runTree(t, tree, withSeed(42)).order(), order)
+	assert.NotEqual(t, runTree(t, tree, withSeed(43)).order(), order)
+}
+
+func TestProbabilityNode(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>loop</id>
+    <ty>LoopNode</ty>
+    <loop>1000</loop>
+    <children>
+      <id>prob</id>
+      <ty>ProbabilityNode</ty>
+      <probability>%v</probability>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+	r := runTree(t, fmt.Sprintf(tree, 0.3), withSeed(7))
+	order := r.order()
+	assert.Equal(t, r.state, Exit)
+	assert.InDelta(t, len(order), 300, 50)
+
+	assert.Equal(t, runTree(t, fmt.Sprintf(tree, 0.3), withSeed(7)).order(), order)
+	assert.Equal(t, runTree(t, fmt.Sprintf(tree, 0), withSeed(7)).order(), "")
+	assert.Equal(t, len(runTree(t, fmt.Sprintf(tree, 1), withSeed(7)).order()), 1000)
+	assert.Equal(t, runTree(t, fmt.Sprintf(tree, 1.5), withSeed(7)).state, Error)
+}
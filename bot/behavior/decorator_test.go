@@ -0,0 +1,381 @@
+package behavior
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryNode(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>retry</id>
+    <ty>RetryNode</ty>
+    <retry>3</retry>
+    <interval>5</interval>
+    <backoff>exponential</backoff>
+    <children>
+      <id>flaky</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  meta.Try = (meta.Try or 0) + 1
+  if meta.Try &lt; 3 then
+    return state.Error, "flaky"
+  end
+  return state.Succ, {}
+end
+      </code>
+      <children>
+        <id>after</id>
+        <ty>ActionNode</ty>
+        <code>
+function execute()
+  meta.After = 1
+end
+        </code>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+	begin := time.Now()
+	r := runTree(t, tree, withMode(Step), withSleep())
+
+	assert.Equal(t, len(r.errs), 0)
+	assert.Equal(t, r.number("Try"), 3)
+	assert.Equal(t, r.number("After"), 1)
+	assert.GreaterOrEqual(t, time.Since(begin), time.Millisecond*15) // 5 + 10
+
+	stats := r.tick.NodeStats()
+	assert.Equal(t, len(stats), 1)
+	assert.Equal(t, stats[0], NodeStat{ID: "retry", Ty: RETRY, Retry: 2})
+}
+
+func TestRetryNodeExhausted(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>retry</id>
+    <ty>RetryNode</ty>
+    <retry>2</retry>
+    <children>
+      <id>fail</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  meta.Try = (meta.Try or 0) + 1
+  return state.Error, "always"
+end
+      </code>
+    </children>
+  </children>
+</behavior>`
+
+	r := runTree(t, tree, withMode(Step), withSleep())
+
+	assert.Equal(t, r.number("Try"), 3)
+	assert.Equal(t, len(r.errs), 1)
+	assert.Equal(t, r.tick.NodeStats()[0].Retry, 2)
+}
+
+func TestTimeoutNode(t *testing.T) {
+	// 超时的脚本被中断，由外层的重试节点重新执行
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>retry</id>
+    <ty>RetryNode</ty>
+    <retry>1</retry>
+    <children>
+      <id>timeout</id>
+      <ty>TimeoutNode</ty>
+      <timeout>50</timeout>
+      <children>
+        <id>slow</id>
+        <ty>ActionNode</ty>
+        <code>
+function execute()
+  meta.Try = (meta.Try or 0) + 1
+  if meta.Try == 1 then
+    while true do end
+  end
+end
+        </code>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+	r := runTree(t, tree, withMode(Step), withSleep())
+	assert.Equal(t, len(r.errs), 0)
+	assert.Equal(t, r.number("Try"), 2)
+
+	stats := map[string]NodeStat{}
+	for _, v := range r.tick.NodeStats() {
+		stats[v.ID] = v
+	}
+	assert.Equal(t, stats["timeout"].Timeout, 1)
+	assert.Equal(t, stats["retry"].Retry, 1)
+}
+
+func TestTimeoutNodeWait(t *testing.T) {
+	// 跨越多个 tick 的子树超时后，跳过剩余的节点
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>timeout</id>
+    <ty>TimeoutNode</ty>
+    <timeout>20</timeout>
+    <children>
+      <id>wait</id>
+      <ty>WaitNode</ty>
+      <wait>1000</wait>
+      <children>
+        <id>skip</id>
+        <ty>ActionNode</ty>
+        <code>
+function execute()
+  meta.Skip = 1
+end
+        </code>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+	begin := time.Now()
+	r := runTree(t, tree, withMode(Step), withSleep())
+
+	assert.Less(t, time.Since(begin), time.Millisecond*500)
+	assert.Equal(t, len(r.errs), 1)
+	assert.Contains(t, r.errs[0], "timeout after 20ms")
+	assert.Equal(t, r.number("Skip"), 0)
+	assert.Equal(t, r.tick.NodeStats()[0].Timeout, 1)
+}
+
+// decoratorTree 在 sequence 中执行装饰节点（%s 为装饰节点的 xml），之后的 after 节点记录 sequence 是否继续执行
+const decoratorTree = `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>recover</id>
+      <ty>AlwaysSucceedNode</ty>%s
+    </children>
+    <children>
+      <id>after</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  meta.After = (meta.After or 0) + 1
+end
+      </code>
+    </children>
+  </children>
+</behavior>`
+
+const (
+	childSucc = `
+function execute()
+  meta.Run = (meta.Run or 0) + 1
+end`
+	childFail = `
+function execute()
+  meta.Run = (meta.Run or 0) + 1
+  return state.Error, "fail"
+end`
+	// 第三次执行时成功
+	childThird = `
+function execute()
+  meta.Run = (meta.Run or 0) + 1
+  if meta.Run &lt; 3 then
+    return state.Error, "fail"
+  end
+end`
+	// 第四次执行时失败
+	childFourth = `
+function execute()
+  meta.Run = (meta.Run or 0) + 1
+  if meta.Run == 4 then
+    return state.Error, "fail"
+  end
+end`
+)
+
+func TestDecoratorNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		deco string
+		run  int
+	}{
+		{"inverter succ", `
+      <children>
+        <id>deco</id>
+        <ty>InverterNode</ty>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childSucc + `</code>
+        </children>
+      </children>`, 1},
+		{"inverter fail", `
+      <children>
+        <id>deco</id>
+        <ty>InverterNode</ty>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childFail + `</code>
+        </children>
+      </children>`, 1},
+		{"always succeed", `
+      <children>
+        <id>deco</id>
+        <ty>AlwaysSucceedNode</ty>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childFail + `</code>
+        </children>
+      </children>`, 1},
+		{"always fail", `
+      <children>
+        <id>deco</id>
+        <ty>AlwaysFailNode</ty>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childSucc + `</code>
+        </children>
+      </children>`, 1},
+		{"until success", `
+      <children>
+        <id>deco</id>
+        <ty>RepeatUntilSuccessNode</ty>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childThird + `</code>
+        </children>
+      </children>`, 3},
+		{"until success limit", `
+      <children>
+        <id>deco</id>
+        <ty>RepeatUntilSuccessNode</ty>
+        <loop>2</loop>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childThird + `</code>
+        </children>
+      </children>`, 2},
+		{"until failure", `
+      <children>
+        <id>deco</id>
+        <ty>RepeatUntilFailureNode</ty>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childFourth + `</code>
+        </children>
+      </children>`, 4},
+		{"until failure limit", `
+      <children>
+        <id>deco</id>
+        <ty>RepeatUntilFailureNode</ty>
+        <loop>3</loop>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childSucc + `</code>
+        </children>
+      </children>`, 3},
+	}
+
+	for _, mode := range []Mode{Thread, Block, Step} {
+		for _, tt := range tests {
+			// 失败被外层的 AlwaysSucceedNode 处理，错误只记录在 thread info 中（uncaught 的数量为 0
+			r := runTree(t, fmt.Sprintf(decoratorTree, tt.deco), withMode(mode), withSleep())
+			assert.Equal(t, r.number("Run"), tt.run, tt.name)
+			assert.Equal(t, r.number("After"), 1, tt.name)
+			assert.Equal(t, len(r.errs), 0, tt.name)
+		}
+	}
+}
+
+func TestDecoratorUncaught(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>inverter</id>
+    <ty>InverterNode</ty>
+    <children>
+      <id>child</id>
+      <ty>ActionNode</ty>
+      <code>` + childSucc + `</code>
+    </children>
+  </children>
+</behavior>`
+
+	r := newRun(t, tree)
+
+	states := []string{}
+	for i := 0; i < 10; i++ {
+		state, end := r.tick.Do()
+		states = append(states, state)
+		if end {
+			break
+		}
+	}
+
+	// 没有被处理的失败作为错误返回（Thread 模式下 bot 会因此结束
+	assert.Contains(t, states, Error)
+}
+
+func TestCooldownNode(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>loop</id>
+    <ty>LoopNode</ty>
+    <loop>3</loop>
+    <children>
+      <id>succ</id>
+      <ty>AlwaysSucceedNode</ty>
+      <children>
+        <id>cooldown</id>
+        <ty>CooldownNode</ty>
+        <cooldown>1000</cooldown>
+        <children>
+          <id>child</id>
+          <ty>ActionNode</ty>
+          <code>` + childSucc + `</code>
+        </children>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+	r := runTree(t, tree, withMode(Step), withSleep())
+	assert.Equal(t, len(r.errs), 0)
+	assert.Equal(t, r.number("Run"), 1)
+}
@@ -0,0 +1,130 @@
+package behavior
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pojol/gobot/bot/pool"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// mark 记录节点的执行顺序
+const testGlobal = `
+meta = {}
+state = { Succ = "Succ", Error = "Error", Break = "Break", Exit = "Exit" }
+
+function mark(id)
+  meta.Order = (meta.Order or "") .. id
+end
+`
+
+type runOptions struct {
+	mode      Mode
+	seed      int64   // 为 0 时不设置
+	thinktime float64 // 等待节点的等待时间倍率
+	sleep     bool    // 每次 tick 之后按照 Tick.Next 休眠（有事件时被唤醒
+}
+
+type runOption func(*runOptions)
+
+func withMode(mode Mode) runOption {
+	return func(o *runOptions) { o.mode = mode }
+}
+
+func withSeed(seed int64) runOption {
+	return func(o *runOptions) { o.seed = seed }
+}
+
+func withThinkTime(m float64) runOption {
+	return func(o *runOptions) { o.thinktime = m }
+}
+
+func withSleep() runOption {
+	return func(o *runOptions) { o.sleep = true }
+}
+
+// treeRun 行为树的执行结果
+type treeRun struct {
+	tree  *Tree
+	tick  *Tick
+	bs    *pool.BotState
+	state string   // 最后一次 tick 的状态
+	errs  []string // 执行过程中的错误信息
+	ticks int
+}
+
+func (r *treeRun) meta() *lua.LTable {
+	return r.bs.L.GetGlobal("meta").(*lua.LTable)
+}
+
+// order 节点的执行顺序（mark
+func (r *treeRun) order() string {
+	return lua.LVAsString(r.meta().RawGetString("Order"))
+}
+
+func (r *treeRun) number(key string) int {
+	return int(lua.LVAsNumber(r.meta().RawGetString(key)))
+}
+
+// newRun 加载行为树，还没有执行
+func newRun(t *testing.T, xml string, opts ...runOption) *treeRun {
+	o := runOptions{mode: Thread, thinktime: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	tree, err := Load([]byte(xml), o.mode)
+	if !assert.Equal(t, err, nil) {
+		t.FailNow()
+	}
+
+	bs := pool.NewState()
+	t.Cleanup(func() { pool.FreeState(bs) })
+	assert.Equal(t, bs.L.DoString(testGlobal), nil)
+
+	bb := &Blackboard{
+		Nods:      []INod{tree.GetRoot()},
+		Threadlst: []ThreadInfo{{Number: 1}},
+	}
+
+	tick := NewTick(bb, bs, "1")
+	if o.seed != 0 {
+		tick.SetSeed(o.seed)
+	}
+	tick.SetThinkTime(o.thinktime)
+
+	return &treeRun{tree: tree, tick: tick, bs: bs}
+}
+
+// runTree 执行行为树直到结束
+func runTree(t *testing.T, xml string, opts ...runOption) *treeRun {
+	o := runOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := newRun(t, xml, opts...)
+	for ; r.ticks < 10000; r.ticks++ {
+		var end bool
+		r.state, end = r.tick.Do()
+		for _, info := range r.tick.blackboard.ThreadInfo() {
+			if info.ErrMsg != "" {
+				r.errs = append(r.errs, info.ErrMsg)
+			}
+		}
+		if end {
+			return r
+		}
+
+		if d := time.Until(r.tick.Next()); o.sleep && d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.bs.Events.Wake():
+			}
+		}
+	}
+
+	t.Fatal("tree not end")
+	return nil
+}
@@ -1,12 +1,17 @@
 package behavior
 
+import (
+	"time"
+)
+
 // Returning status
 type NodStatus int
 
 const (
-	NSSucc NodStatus = 1 + iota
-	NSErr
-	NSFail
+	NSSucc    NodStatus = 1 + iota // 执行成功
+	NSErr                          // 执行出错（脚本运行时错误
+	NSFail                         // 执行失败（脚本返回 state.Error，条件不满足 ...
+	NSRunning                      // 正在执行（节点或者子节点还在执行中
 )
 
 const (
@@ -18,6 +23,18 @@ const (
 	LOOP      = "LoopNode"
 	PARALLEL  = "ParallelNode"
 	SCRIPT    = "ScriptNode"
+	TIMEOUT   = "TimeoutNode"
+	RETRY     = "RetryNode"
+	INVERTER  = "InverterNode"
+	SUCCEEDER = "AlwaysSucceedNode"
+	FAILER    = "AlwaysFailNode"
+	UNTILSUCC = "RepeatUntilSuccessNode"
+	UNTILFAIL = "RepeatUntilFailureNode"
+	COOLDOWN  = "CooldownNode"
+	RANDSEL   = "RandomSelectorNode"
+	PROB      = "ProbabilityNode"
+	SUBTREE   = "SubtreeNode"
+	WAITEVENT = "WaitForEventNode"
 )
 
 //
@@ -40,9 +57,21 @@ type INod interface {
 	onReset()
 }
 
+// catcher 可以处理子节点失败的节点（RetryNode
+type catcher interface {
+	catch(*Tick, error) bool
+}
+
+// waiter 需要等待到某个时间才能继续执行的节点（WaitNode、RetryNode 的重试间隔 ...
+// 返回空的时间表示可以立即执行
+type waiter interface {
+	due() time.Time
+}
+
 type Node struct {
-	id string
-	ty string
+	id     string
+	ty     string
+	origin string // 子树中的节点对应的子树节点 id
 
 	child  []INod
 	parent INod
@@ -51,11 +80,14 @@ type Node struct {
 
 	freeze       bool
 	threadNumber int
+
+	status NodStatus
 }
 
 func (n *Node) Init(t *Tree, parent INod, mode Mode) {
 	n.id = t.ID
 	n.ty = t.Ty
+	n.origin = t.Origin
 	n.mode = mode
 
 	n.parent = parent
@@ -69,6 +101,17 @@ func (a *Node) Type() string {
 	return a.ty
 }
 
+// Status 节点最近一次执行的结果
+func (a *Node) Status() NodStatus {
+	return a.status
+}
+
+// finish 节点执行成功，交还给父节点
+func (a *Node) finish(t *Tick) {
+	a.status = NSSucc
+	a.parent.onNext(t)
+}
+
 func (a *Node) GetFreeze() bool {
 	return a.freeze
 }
@@ -123,6 +166,18 @@ var actionFactory map[string]CreateActionFunc = map[string]CreateActionFunc{
 	LOOP:      func() interface{} { return &LoopAction{} },
 	PARALLEL:  func() interface{} { return &ParallelAction{} },
 	SCRIPT:    func() interface{} { return &ScriptAction{} },
+	TIMEOUT:   func() interface{} { return &TimeoutAction{} },
+	RETRY:     func() interface{} { return &RetryAction{} },
+	INVERTER:  func() interface{} { return &InverterAction{} },
+	SUCCEEDER: func() interface{} { return &AlwaysAction{succ: true} },
+	FAILER:    func() interface{} { return &AlwaysAction{} },
+	UNTILSUCC: func() interface{} { return &RepeatUntilAction{untilSucc: true} },
+	UNTILFAIL: func() interface{} { return &RepeatUntilAction{} },
+	COOLDOWN:  func() interface{} { return &CooldownAction{} },
+	RANDSEL:   func() interface{} { return &RandomSelectAction{} },
+	PROB:      func() interface{} { return &ProbabilityAction{} },
+	SUBTREE:   func() interface{} { return &SubtreeAction{} },
+	WAITEVENT: func() interface{} { return &WaitForEventAction{} },
 }
 
 func NewNode(name string) interface{} {
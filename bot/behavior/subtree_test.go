@@ -0,0 +1,201 @@
+package behavior
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useSubtrees(t *testing.T, trees map[string]string) {
+	SetSubtreeLoader(func(name string) ([]byte, error) {
+		if f, ok := trees[name]; ok {
+			return []byte(f), nil
+		}
+		return nil, errors.New("not found")
+	})
+	t.Cleanup(func() { SetSubtreeLoader(nil) })
+}
+
+// cyclicSubtrees a 和 b 互相引用
+var cyclicSubtrees = map[string]string{
+	"a": `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>b</id>
+    <ty>SubtreeNode</ty>
+    <ref>b</ref>
+  </children>
+</behavior>`,
+	"b": `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>a</id>
+    <ty>SubtreeNode</ty>
+    <ref>a</ref>
+  </children>
+</behavior>`,
+}
+
+func TestSubtreeNode(t *testing.T) {
+	useSubtrees(t, map[string]string{
+		"login": `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>l</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  mark("l" .. meta.Server .. tostring(meta.Retry + 1) .. tostring(meta.Debug))
+end
+      </code>
+    </children>
+    <children>
+      <id>p</id>
+      <ty>SubtreeNode</ty>
+      <ref>profile</ref>
+    </children>
+  </children>
+</behavior>`,
+		"profile": `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>p</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("p") end</code>
+    </children>
+  </children>
+</behavior>`,
+	})
+
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>s1</id>
+      <ty>SubtreeNode</ty>
+      <ref>login</ref>
+      <params><key>Server</key><value>s</value></params>
+      <params><key>Retry</key><value>1</value></params>
+      <params><key>Debug</key><value>true</value></params>
+    </children>
+    <children>
+      <id>s2</id>
+      <ty>SubtreeNode</ty>
+      <ref>profile</ref>
+    </children>
+  </children>
+</behavior>`)
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "als2truepp")
+
+	// 被引用的节点 id 加上了子树节点的前缀
+	s1 := r.tree.GetRoot().getBase().Children()[0].getBase().Children()[1]
+	assert.Equal(t, s1.getBase().Children()[0].getBase().ID(), "s1/seq")
+	p := s1.getBase().Children()[0].getBase().Children()[1]
+	assert.Equal(t, p.getBase().Children()[0].getBase().ID(), "s1/p/seq")
+}
+
+func TestSubtreeLoadErr(t *testing.T) {
+	useSubtrees(t, cyclicSubtrees)
+
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>s</id>
+    <ty>SubtreeNode</ty>
+    <ref>%v</ref>
+  </children>
+</behavior>`
+
+	_, err := Load([]byte(fmt.Sprintf(tree, "a")), Thread)
+	assert.EqualError(t, err, "s node SubtreeNode s/b/a node SubtreeNode cyclic reference a -> b -> a")
+
+	_, err = Load([]byte(fmt.Sprintf(tree, "unknow")), Thread)
+	assert.NotEqual(t, err, nil)
+
+	_, err = Load([]byte(fmt.Sprintf(tree, "")), Thread)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestSubtreeExpand(t *testing.T) {
+	useSubtrees(t, map[string]string{
+		"login": `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>retry</id>
+    <ty>RetryNode</ty>
+    <retry>1</retry>
+    <children>
+      <id>l</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  mark("l")
+  if meta.Order == "l" then
+    return state.Error, "first login"
+  end
+end
+      </code>
+    </children>
+  </children>
+</behavior>`,
+	})
+
+	f, err := Expand([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <http><shared>true</shared></http>
+  <children>
+    <id>s</id>
+    <ty>SubtreeNode</ty>
+    <ref>login</ref>
+  </children>
+</behavior>`))
+	assert.Equal(t, err, nil)
+
+	// 展开后的行为树不会再获取子树
+	SetSubtreeLoader(nil)
+
+	r := runTree(t, string(f), withSleep())
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "ll")
+
+	// 根节点的 http 配置原样保留
+	http, ok := r.tree.HttpOptions()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, string(http), "<shared>true</shared>")
+
+	// 子树中节点的统计记录在子树节点上
+	assert.Equal(t, r.tick.NodeStats(), []NodeStat{{ID: "s", Ty: SUBTREE, Retry: 1}})
+}
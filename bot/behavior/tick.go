@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
+	"time"
 
 	"github.com/pojol/gobot/bot/pool"
 	"github.com/pojol/gobot/utils"
@@ -14,6 +16,23 @@ type Tick struct {
 	blackboard *Blackboard
 	bs         *pool.BotState
 	botid      string
+
+	stats map[string]*NodeStat
+	rand  *rand.Rand // 随机节点使用的随机数
+
+	thinktime float64 // 等待节点的等待时间倍率
+
+	nods    []INod // 本次 tick 执行的节点
+	aborted []INod // 本次 tick 中被中断的子树
+	failed  bool   // 本次 tick 中有没有被处理的失败
+}
+
+// NodeStat 节点的运行统计（重试、超时次数
+type NodeStat struct {
+	ID      string
+	Ty      string
+	Retry   int
+	Timeout int
 }
 
 var (
@@ -25,10 +44,219 @@ func NewTick(bb *Blackboard, state *pool.BotState, botid string) *Tick {
 		blackboard: bb,
 		bs:         state,
 		botid:      botid,
+		stats:      make(map[string]*NodeStat),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		thinktime:  1,
 	}
 	return t
 }
 
+// SetThinkTime 设置等待节点的等待时间倍率（0 不等待，用于功能测试；1 按照配置的时间等待，用于压力测试
+func (t *Tick) SetThinkTime(m float64) {
+	if m < 0 {
+		m = 0
+	}
+	t.thinktime = m
+}
+
+// SetSeed 设置随机数种子
+func (t *Tick) SetSeed(seed int64) {
+	t.rand = rand.New(rand.NewSource(seed))
+}
+
+func (t *Tick) stat(n INod) *NodeStat {
+	if t.stats == nil {
+		t.stats = make(map[string]*NodeStat)
+	}
+
+	// 子树中节点的统计记录在编辑器中的子树节点上
+	id, ty := n.getBase().ID(), n.getType()
+	if origin := n.getBase().origin; origin != "" {
+		id, ty = origin, SUBTREE
+	}
+
+	if _, ok := t.stats[id]; !ok {
+		t.stats[id] = &NodeStat{ID: id, Ty: ty}
+	}
+	return t.stats[id]
+}
+
+// NodeStats 获取节点的运行统计
+func (t *Tick) NodeStats() []NodeStat {
+	lst := []NodeStat{}
+	for _, v := range t.stats {
+		lst = append(lst, *v)
+	}
+	return lst
+}
+
+// timeoutScope 获取最近的正在计时的超时祖先节点
+func (t *Tick) timeoutScope(n INod) *TimeoutAction {
+	for p := n.getBase().parent; p != nil; p = p.getBase().parent {
+		if a, ok := p.(*TimeoutAction); ok && a.ctx != nil {
+			return a
+		}
+	}
+	return nil
+}
+
+// expired 获取已经超时的最外层的超时祖先节点
+func (t *Tick) expired(n INod) *TimeoutAction {
+	var owner *TimeoutAction
+	now := time.Now()
+
+	for p := n.getBase().parent; p != nil; p = p.getBase().parent {
+		if a, ok := p.(*TimeoutAction); ok && a.ctx != nil && !now.Before(a.endtime) {
+			owner = a
+		}
+	}
+
+	return owner
+}
+
+// tickNode 执行节点，节点处于超时节点之下时，脚本的执行会在截止时间被中断
+func (t *Tick) tickNode(n INod) error {
+	scope := t.timeoutScope(n)
+	if scope == nil {
+		return n.onTick(t)
+	}
+
+	// ctx 在定时器触发后才会被取消，以截止时间为准（定时器可能还没有被调度
+	var err error
+	if scope.ctx.Err() == nil && t.expired(n) == nil {
+		t.bs.L.SetContext(scope.ctx)
+		err = n.onTick(t)
+		t.bs.L.RemoveContext()
+	}
+
+	if owner := t.expired(n); owner != nil || scope.ctx.Err() != nil {
+		if owner == nil {
+			owner = scope
+		}
+
+		t.stat(owner).Timeout++
+		return &timeoutError{owner: owner}
+	}
+
+	return err
+}
+
+// Next 下一次需要执行 tick 的时间，等待中的节点（WaitNode、WaitForEventNode ...）没有到期时可以休眠到这个时间
+// 超时节点的截止时间同样会唤醒等待中的子节点
+func (t *Tick) Next() time.Time {
+	now := time.Now()
+	next := time.Time{}
+
+	for _, n := range t.blackboard.Nods {
+		due := now
+		if w, ok := n.(waiter); ok && !w.due().IsZero() {
+			due = w.due()
+		}
+
+		for p := n.getBase().parent; p != nil; p = p.getBase().parent {
+			if a, ok := p.(*TimeoutAction); ok && a.ctx != nil && a.endtime.Before(due) {
+				due = a.endtime
+			}
+		}
+
+		if next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+
+	if next.IsZero() {
+		return now
+	}
+	return next
+}
+
+// raise 节点失败时调用，交给最近的可以处理失败的祖先节点（catcher
+// 返回 false 表示没有节点处理这个失败（作为错误返回
+func (t *Tick) raise(n INod, err error) bool {
+	if n.getBase().status != NSErr {
+		n.getBase().status = NSFail
+	}
+
+	branch := n
+	for p := n.getBase().parent; p != nil; branch, p = p, p.getBase().parent {
+		if c, ok := p.(catcher); ok && c.catch(t, err) {
+			t.abort(branch)
+
+			if _, ok := p.(*RootAction); ok {
+				// 失败传递到了根节点，作为错误记录
+				t.blackboard.threadErr(n.getBase().getThread(), n.getBase().ID(), err.Error())
+			} else {
+				t.blackboard.ThreadFillInfo(ThreadInfo{
+					Number: n.getBase().getThread(),
+					CurNod: n.getBase().ID(),
+					Change: fmt.Sprintf("%v node %v catch %v", p.getBase().ID(), p.getType(), err.Error()),
+				})
+			}
+
+			p.onNext(t)
+			return true
+		}
+
+		p.getBase().status = NSFail
+	}
+
+	t.failed = true
+	t.blackboard.threadErr(n.getBase().getThread(), n.getBase().ID(), err.Error())
+	return false
+}
+
+// fail 处理节点执行时产生的失败
+func (t *Tick) fail(n INod, err error) {
+	var terr *timeoutError
+	if errors.As(err, &terr) {
+		// 超时的是 TimeoutNode 本身，中断子树后由超时节点继续
+		if !t.raise(terr.owner, err) {
+			t.abort(terr.owner.base.Children()[0])
+			terr.owner.onNext(t)
+		}
+		return
+	}
+
+	if !t.raise(n, err) {
+		n.onNext(t)
+	}
+}
+
+// abort 中断以 branch 为根的分支，移除分支中已经加入的节点，本次 tick 中不再处理分支中的节点
+func (t *Tick) abort(branch INod) {
+	t.aborted = append(t.aborted, branch)
+
+	nods := t.blackboard.Nods[:0]
+	for _, n := range t.blackboard.Nods {
+		if !inBranch(n, []INod{branch}) {
+			nods = append(nods, n)
+		}
+	}
+	t.blackboard.Nods = nods
+
+	t.release(branch)
+}
+
+// release 移除被中断的子树中 Parallel 节点创建的线程
+func (t *Tick) release(n INod) {
+	if p, ok := n.(*ParallelAction); ok {
+		p.release(t)
+	}
+
+	for _, child := range n.getBase().Children() {
+		t.release(child)
+	}
+}
+
+func isDescendant(n INod, ancestor INod) bool {
+	for p := n.getBase().parent; p != nil; p = p.getBase().parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Tick) stateCheck(mode Mode, ty string) (string, string, error) {
 
 	var r1, r2 lua.LValue
@@ -84,14 +312,18 @@ ext:
 
 func (t *Tick) Do() (state string, end bool) {
 
-	nods := t.blackboard.GetOpenNods()
+	// 复制一份，onNext 中加入的节点不会覆盖正在遍历的节点
+	t.nods = append(t.nods[:0], t.blackboard.GetOpenNods()...)
 	t.blackboard.ThreadInfoReset()
+	t.aborted = t.aborted[:0]
+	t.failed = false
 
 	var err, parseerr error
 	var msg string
+	var fails map[INod]error
 
-	for _, n := range nods {
-		err = n.onTick(t)
+	for _, n := range t.nods {
+		err = t.tickNode(n)
 
 		state, msg, parseerr = t.stateCheck(n.getBase().getMode(), n.getType())
 
@@ -101,6 +333,14 @@ func (t *Tick) Do() (state string, end bool) {
 			Change: msg,
 		}
 
+		if status, ferr := t.status(n, err, state, msg); status != NSSucc {
+			if fails == nil {
+				fails = make(map[INod]error)
+			}
+			n.getBase().status = status
+			fails[n] = ferr
+		}
+
 		if err != nil {
 			threadInfo.ErrMsg = fmt.Sprintf("tick err %v", err.Error())
 			fmt.Println("tick err", threadInfo.ErrMsg)
@@ -117,7 +357,7 @@ func (t *Tick) Do() (state string, end bool) {
 				end = true
 				threadInfo.ErrMsg = fmt.Sprintf("script break err %v", msg)
 				fmt.Println("tick break err", threadInfo.ErrMsg)
-			} else if state == Error {
+			} else if state == Error && err == nil {
 				// 节点脚本出错，脚本逻辑自行抛出的错误
 				threadInfo.ErrMsg = fmt.Sprintf("script err %v", msg)
 				fmt.Println("tick script err", threadInfo.ErrMsg)
@@ -132,12 +372,29 @@ func (t *Tick) Do() (state string, end bool) {
 
 	t.blackboard.Reset()
 
-	for _, n := range nods {
-		n.onNext(t)
+	for _, n := range t.nods {
+		if inBranch(n, t.aborted) {
+			continue
+		}
+
+		if ferr, ok := fails[n]; ok {
+			t.fail(n, ferr)
+		} else {
+			n.onNext(t)
+		}
+	}
+
+	// 失败被处理时不作为错误返回
+	if t.failed {
+		state = Error
+	} else if state == Error {
+		state = Succ
 	}
 
 	if t.blackboard.end {
-		state = Exit
+		if !t.failed {
+			state = Exit
+		}
 		end = true
 		goto ext
 	}
@@ -145,3 +402,30 @@ func (t *Tick) Do() (state string, end bool) {
 ext:
 	return state, end
 }
+
+// status 节点执行的结果，脚本节点 state.Succ 为成功，state.Error 为失败，条件节点返回 false 为失败
+func (t *Tick) status(n INod, err error, state string, msg string) (NodStatus, error) {
+	if err != nil {
+		return NSErr, err
+	}
+
+	if state == Error {
+		return NSFail, fmt.Errorf("script err %v", msg)
+	}
+
+	if c, ok := n.(*ConditionAction); ok && !c.succ {
+		return NSFail, fmt.Errorf("%v node %v condition not satisfied", c.base.ID(), c.base.Type())
+	}
+
+	return NSSucc, nil
+}
+
+// inBranch 节点是否在以 roots 中某个节点为根的分支中
+func inBranch(n INod, roots []INod) bool {
+	for _, r := range roots {
+		if n == r || isDescendant(n, r) {
+			return true
+		}
+	}
+	return false
+}
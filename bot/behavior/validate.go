@@ -0,0 +1,176 @@
+package behavior
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// 问题的级别
+const (
+	ProblemError   = "error"   // 行为树不能正确执行
+	ProblemWarning = "warning" // 可以执行，但可能不是预期的行为
+)
+
+// Problem 行为树中的问题（编辑器根据 ID 高亮节点
+type Problem struct {
+	ID    string
+	Ty    string
+	Level string
+	Msg   string
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%v node %v %v", p.ID, p.Ty, p.Msg)
+}
+
+func (t *Tree) problem(format string, args ...interface{}) *Problem {
+	return t.report(ProblemError, fmt.Sprintf(format, args...))
+}
+
+// report 子树中的问题记录在编辑器中的子树节点上（编辑器只能高亮这个节点，子树中的节点 id 记录在 Msg 中
+func (t *Tree) report(level string, msg string) *Problem {
+	if t.Origin != "" {
+		return &Problem{
+			ID:    t.Origin,
+			Ty:    SUBTREE,
+			Level: level,
+			Msg:   fmt.Sprintf("%v node %v %v", t.ID, t.Ty, msg),
+		}
+	}
+
+	return &Problem{
+		ID:    t.ID,
+		Ty:    t.Ty,
+		Level: level,
+		Msg:   msg,
+	}
+}
+
+// checker 可以在执行前检查配置的节点
+type checker interface {
+	check() error
+}
+
+// ACTION 编辑器中的脚本节点
+const ACTION = "ActionNode"
+
+// 只会执行第一个子节点的节点
+var singleChild = map[string]bool{
+	CONDITION: true,
+	WAIT:      true,
+	LOOP:      true,
+	TIMEOUT:   true,
+	RETRY:     true,
+	INVERTER:  true,
+	SUCCEEDER: true,
+	FAILER:    true,
+	UNTILSUCC: true,
+	UNTILFAIL: true,
+	COOLDOWN:  true,
+	PROB:      true,
+	WAITEVENT: true,
+}
+
+// 没有子节点时没有意义的节点
+var needChild = map[string]bool{
+	TIMEOUT:   true,
+	RETRY:     true,
+	INVERTER:  true,
+	UNTILSUCC: true,
+	UNTILFAIL: true,
+	COOLDOWN:  true,
+	PROB:      true,
+}
+
+// HasError 是否有 error 级别的问题
+func HasError(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Level == ProblemError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查行为树，返回所有的问题（没有问题时为空
+// scripts 为作为脚本节点执行的节点类型（编辑器中的预制节点
+func Validate(f []byte, scripts ...string) []Problem {
+	problems := []Problem{}
+
+	tree := &Tree{}
+	err := xml.Unmarshal(f, tree)
+	if err != nil {
+		return append(problems, Problem{Level: ProblemError, Msg: fmt.Sprintf("parse behavior err %v", err)})
+	}
+
+	if tree.Ty != ROOT {
+		problems = append(problems, *tree.problem("root node must be %v", ROOT))
+	}
+
+	err = tree.expand(nil)
+	if err != nil {
+		if p, ok := err.(*Problem); ok {
+			return append(problems, *p)
+		}
+		return append(problems, Problem{Level: ProblemError, Msg: err.Error()})
+	}
+
+	types := map[string]bool{SCRIPT: true, ACTION: true}
+	for _, ty := range scripts {
+		types[ty] = true
+	}
+
+	root := NewNode(tree.Ty).(INod)
+	tree.link(root, nil, Thread)
+
+	return tree.validate(root, types, problems)
+}
+
+func (t *Tree) validate(n INod, scripts map[string]bool, problems []Problem) []Problem {
+	report := func(level string, msg string) {
+		problems = append(problems, *t.report(level, msg))
+	}
+
+	_, known := actionFactory[t.Ty]
+	if !known && !scripts[t.Ty] {
+		report(ProblemError, fmt.Sprintf("unknow node type %v", t.Ty))
+	}
+
+	switch n.(type) {
+	case *ScriptAction, *ConditionAction:
+		_, err := parse.Parse(strings.NewReader(t.Code), t.ID)
+		if err != nil {
+			report(ProblemError, fmt.Sprintf("lua syntax err %v", err))
+		}
+	}
+
+	if c, ok := n.(checker); ok {
+		if err := c.check(); err != nil {
+			report(ProblemError, strings.TrimPrefix(err.Error(), fmt.Sprintf("%v node %v ", t.ID, t.Ty)))
+		}
+	}
+
+	if singleChild[t.Ty] && len(t.Children) > 1 {
+		report(ProblemError, fmt.Sprintf("has %v children, only one child is allowed", len(t.Children)))
+	}
+	if needChild[t.Ty] && len(t.Children) == 0 {
+		report(ProblemWarning, "without child")
+	}
+
+	if t.Ty == SELETE {
+		for _, child := range t.Children {
+			if child.Ty != CONDITION {
+				problems = append(problems, *child.report(ProblemWarning, fmt.Sprintf("child of %v %v is not a %v", SELETE, t.ID, CONDITION)))
+			}
+		}
+	}
+
+	for k, child := range t.Children {
+		problems = child.validate(n.getBase().Children()[k], scripts, problems)
+	}
+
+	return problems
+}
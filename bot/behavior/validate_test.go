@@ -0,0 +1,268 @@
+package behavior
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func problemIDs(problems []Problem, level string) []string {
+	ids := []string{}
+	for _, p := range problems {
+		if p.Level == level {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+func TestValidate(t *testing.T) {
+	problems := Validate([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return true end</code>
+    </children>
+  </children>
+</behavior>`))
+	assert.Equal(t, len(problems), 0)
+	assert.Equal(t, HasError(problems), false)
+
+	// 不是合法的 xml 时不会 panic
+	problems = Validate([]byte("<behavior><id>"))
+	assert.Equal(t, HasError(problems), true)
+
+	_, err := Load([]byte("<behavior><id>"), Thread)
+	assert.NotEqual(t, err, nil)
+
+	problems = Validate([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() if then end</code>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>UnknowNode</ty>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>LoopNode</ty>
+      <loop>-1</loop>
+    </children>
+    <children>
+      <id>d</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return true end</code>
+      <children>
+        <id>d1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("d1") end</code>
+      </children>
+      <children>
+        <id>d2</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("d2") end</code>
+      </children>
+    </children>
+    <children>
+      <id>e</id>
+      <ty>ParallelNode</ty>
+      <policy>some</policy>
+    </children>
+    <children>
+      <id>f</id>
+      <ty>ProbabilityNode</ty>
+      <probability>2</probability>
+      <children>
+        <id>f1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("f1") end</code>
+      </children>
+    </children>
+    <children>
+      <id>g</id>
+      <ty>WaitForEventNode</ty>
+    </children>
+    <children>
+      <id>h</id>
+      <ty>WaitNode</ty>
+      <distribution>uniform</distribution>
+      <min>10</min>
+      <max>5</max>
+    </children>
+    <children>
+      <id>i</id>
+      <ty>RandomSelectorNode</ty>
+      <children>
+        <id>i1</id>
+        <ty>ActionNode</ty>
+        <weight>0</weight>
+        <code>function execute() mark("i1") end</code>
+      </children>
+    </children>
+    <children>
+      <id>j</id>
+      <ty>TimeoutNode</ty>
+      <children>
+        <id>j1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("j1") end</code>
+      </children>
+    </children>
+    <children>
+      <id>k</id>
+      <ty>RetryNode</ty>
+      <retry>-1</retry>
+      <children>
+        <id>k1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("k1") end</code>
+      </children>
+    </children>
+    <children>
+      <id>l</id>
+      <ty>RetryNode</ty>
+      <retry>1</retry>
+      <interval>-10</interval>
+      <children>
+        <id>l1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("l1") end</code>
+      </children>
+    </children>
+    <children>
+      <id>m</id>
+      <ty>CooldownNode</ty>
+      <cooldown>-10</cooldown>
+      <children>
+        <id>m1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("m1") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`))
+
+	assert.Equal(t, problemIDs(problems, ProblemError), []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m"})
+	assert.Equal(t, problems[1].Msg, "unknow node type UnknowNode")
+	assert.Equal(t, problems[2].Msg, "invalid loop -1")
+	assert.Equal(t, problems[9].Msg, "invalid timeout 0")
+	assert.Equal(t, problems[10].Msg, "invalid retry -1")
+	assert.Equal(t, problems[11].Msg, "invalid interval -10")
+	assert.Equal(t, problems[12].Msg, "invalid cooldown -10")
+}
+
+func TestValidateWarning(t *testing.T) {
+	problems := Validate([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>sel</id>
+    <ty>SelectorNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ConditionNode</ty>
+      <code>function execute() return false end</code>
+      <children>
+        <id>a1</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a1") end</code>
+      </children>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>InverterNode</ty>
+    </children>
+  </children>
+</behavior>`))
+
+	assert.Equal(t, HasError(problems), false)
+	assert.Equal(t, problemIDs(problems, ProblemWarning), []string{"b", "c", "c"})
+}
+
+func TestValidatePrefab(t *testing.T) {
+	tree := `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>HTTPPost</ty>
+      <code>function execute() end</code>
+    </children>
+  </children>
+</behavior>`
+
+	assert.Equal(t, problemIDs(Validate([]byte(tree)), ProblemError), []string{"a"})
+	assert.Equal(t, len(Validate([]byte(tree), "HTTPPost")), 0)
+}
+
+func TestValidateSubtree(t *testing.T) {
+	useSubtrees(t, cyclicSubtrees)
+
+	problems := Validate([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>s</id>
+    <ty>SubtreeNode</ty>
+    <ref>a</ref>
+  </children>
+</behavior>`))
+	assert.Equal(t, problemIDs(problems, ProblemError), []string{"s"})
+	assert.Equal(t, problems[0].Msg, "s/b/a node SubtreeNode cyclic reference a -> b -> a")
+
+	// 子树中的问题记录在子树节点上
+	useSubtrees(t, map[string]string{
+		"loop": `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>c</id>
+    <ty>LoopNode</ty>
+    <loop>-1</loop>
+  </children>
+</behavior>`,
+	})
+
+	problems = Validate([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>s</id>
+    <ty>SubtreeNode</ty>
+    <ref>loop</ref>
+  </children>
+</behavior>`))
+	assert.Equal(t, problems, []Problem{{ID: "s", Ty: SUBTREE, Level: ProblemError, Msg: "s/c node LoopNode invalid loop -1"}})
+}
@@ -0,0 +1,185 @@
+package behavior
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForEventNode(t *testing.T) {
+	begin := time.Now()
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") require("event").after("ready", 0.1) end</code>
+    </children>
+    <children>
+      <id>wait</id>
+      <ty>WaitForEventNode</ty>
+      <event>ready</event>
+      <timeout>1000</timeout>
+      <children>
+        <id>b</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("b") end</code>
+      </children>
+    </children>
+    <children>
+      <id>c</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("c") end</code>
+    </children>
+  </children>
+</behavior>`, withSleep())
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "abc")
+	assert.GreaterOrEqual(t, time.Since(begin), 100*time.Millisecond)
+	assert.Less(t, time.Since(begin), 500*time.Millisecond)
+	assert.Less(t, r.ticks, 20) // 等待期间没有轮询
+
+	// 在等待之前触发的事件同样有效
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") require("event").signal("ready") end</code>
+    </children>
+    <children>
+      <id>wait</id>
+      <ty>WaitForEventNode</ty>
+      <event>ready</event>
+      <timeout>0</timeout>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+  </children>
+</behavior>`, withSleep())
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "ab")
+}
+
+func TestWaitForEventTimeout(t *testing.T) {
+	begin := time.Now()
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>a</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("a") end</code>
+    </children>
+    <children>
+      <id>wait</id>
+      <ty>WaitForEventNode</ty>
+      <event>never</event>
+      <timeout>100</timeout>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+  </children>
+</behavior>`, withSleep())
+
+	assert.Equal(t, r.state, Error)
+	assert.Equal(t, r.order(), "a")
+	assert.GreaterOrEqual(t, time.Since(begin), 100*time.Millisecond)
+	assert.Less(t, r.ticks, 20)
+
+	// 超时的失败可以被重试节点处理
+	r = runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>retry</id>
+      <ty>RetryNode</ty>
+      <retry>3</retry>
+      <children>
+        <id>s</id>
+        <ty>SequenceNode</ty>
+        <children>
+          <id>a</id>
+          <ty>ActionNode</ty>
+          <code>
+function execute()
+  mark("a")
+  if meta.Order == "aaa" then
+    require("event").signal("ready")
+  end
+end
+          </code>
+        </children>
+        <children>
+          <id>wait</id>
+          <ty>WaitForEventNode</ty>
+          <event>ready</event>
+          <timeout>50</timeout>
+        </children>
+      </children>
+    </children>
+    <children>
+      <id>b</id>
+      <ty>ActionNode</ty>
+      <code>function execute() mark("b") end</code>
+    </children>
+  </children>
+</behavior>`, withSleep())
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "aaab")
+}
+
+func TestTickNextUnderTimeout(t *testing.T) {
+	r := newRun(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>timeout</id>
+    <ty>TimeoutNode</ty>
+    <timeout>100</timeout>
+    <children>
+      <id>wait</id>
+      <ty>WaitForEventNode</ty>
+      <event>never</event>
+      <timeout>0</timeout>
+    </children>
+  </children>
+</behavior>`)
+
+	timeout := r.tree.GetRoot().getBase().Children()[0].(*TimeoutAction)
+	for i := 0; i < 3; i++ {
+		r.tick.Do()
+	}
+
+	// 没有超时时间的等待节点在超时节点的截止时间被唤醒
+	assert.WithinDuration(t, r.tick.Next(), timeout.endtime, time.Millisecond)
+}
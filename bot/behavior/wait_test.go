@@ -0,0 +1,132 @@
+package behavior
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitNode(fields string) *WaitAction {
+	tree, _ := Load([]byte(`
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>wait</id>
+    <ty>WaitNode</ty>`+fields+`
+  </children>
+</behavior>`), Thread)
+
+	return tree.GetRoot().getBase().Children()[0].(*WaitAction)
+}
+
+// samples 获取等待时间的均值和标准差
+func samples(a *WaitAction, tick *Tick, n int) (float64, float64) {
+	var sum, sq float64
+	for i := 0; i < n; i++ {
+		d := float64(a.duration(tick, 0))
+		sum += d
+		sq += d * d
+	}
+
+	mean := sum / float64(n)
+	return mean, math.Sqrt(sq/float64(n) - mean*mean)
+}
+
+func TestWaitDistribution(t *testing.T) {
+	tick := NewTick(&Blackboard{}, nil, "1")
+	tick.SetSeed(1)
+
+	a := waitNode(`<wait>100</wait>`)
+	assert.Equal(t, a.check(), nil)
+	assert.Equal(t, a.duration(tick, 0), int64(100))
+
+	a = waitNode(`<distribution>uniform</distribution><min>100</min><max>300</max>`)
+	for i := 0; i < 1000; i++ {
+		d := a.duration(tick, 0)
+		assert.True(t, d >= 100 && d <= 300)
+	}
+	mean, _ := samples(a, tick, 10000)
+	assert.InDelta(t, mean, 200, 5)
+
+	a = waitNode(`<distribution>normal</distribution><wait>1000</wait><stddev>100</stddev>`)
+	mean, stddev := samples(a, tick, 10000)
+	assert.InDelta(t, mean, 1000, 5)
+	assert.InDelta(t, stddev, 100, 5)
+
+	a = waitNode(`<distribution>exponential</distribution><wait>500</wait>`)
+	mean, stddev = samples(a, tick, 10000)
+	assert.InDelta(t, mean, 500, 20)
+	assert.InDelta(t, stddev, 500, 30)
+
+	// think time 倍率
+	tick.SetThinkTime(0)
+	mean, _ = samples(a, tick, 100)
+	assert.Equal(t, mean, float64(0))
+
+	tick.SetThinkTime(2)
+	mean, _ = samples(waitNode(`<wait>100</wait>`), tick, 10)
+	assert.Equal(t, mean, float64(200))
+
+	assert.NotEqual(t, waitNode(`<distribution>gamma</distribution>`).check(), nil)
+	assert.NotEqual(t, waitNode(`<distribution>uniform</distribution><min>300</min><max>100</max>`).check(), nil)
+	assert.NotEqual(t, waitNode(`<wait>-1</wait>`).check(), nil)
+}
+
+func TestWaitPoissonPacing(t *testing.T) {
+	tick := NewTick(&Blackboard{}, nil, "1")
+	tick.SetSeed(1)
+
+	a := waitNode(`<distribution>poisson</distribution><wait>1000</wait>`)
+
+	// 节拍从第一次执行开始计算，执行节点花费的时间不会推迟下一个节拍
+	now := int64(10000)
+	var last int64
+	for i := 0; i < 10000; i++ {
+		d := a.duration(tick, now)
+		assert.True(t, d >= 0)
+		now += d
+		assert.Equal(t, now, a.pacetime)
+
+		last = now
+		now += 1 // 节点的执行时间
+	}
+	assert.InDelta(t, float64(last-10000)/10000, 1000, 50)
+
+	// 落后于节拍时不补偿
+	pace := a.pacetime
+	d := a.duration(tick, pace+100000)
+	assert.Equal(t, a.pacetime, pace+100000+d)
+}
+
+func TestWaitThinkTimeZero(t *testing.T) {
+	begin := time.Now()
+	r := runTree(t, `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>loop</id>
+    <ty>LoopNode</ty>
+    <loop>3</loop>
+    <children>
+      <id>wait</id>
+      <ty>WaitNode</ty>
+      <distribution>normal</distribution>
+      <wait>60000</wait>
+      <stddev>1000</stddev>
+      <children>
+        <id>a</id>
+        <ty>ActionNode</ty>
+        <code>function execute() mark("a") end</code>
+      </children>
+    </children>
+  </children>
+</behavior>`, withThinkTime(0))
+
+	assert.Equal(t, r.state, Exit)
+	assert.Equal(t, r.order(), "aaa")
+	assert.Less(t, time.Since(begin), time.Second)
+}
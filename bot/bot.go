@@ -35,6 +35,8 @@ type Bot struct {
 	sync.RWMutex
 	bs *pool.BotState // lua state pool
 
+	report []script.Report // 关闭时取出的报告（state 已经被释放
+
 }
 
 const (
@@ -101,6 +103,11 @@ func (b *Bot) GetThreadInfo() string {
 	return string(info)
 }
 
+// builtinScripts 已经由 go 实现并预置在 state 中的脚本，加载 script 目录时跳过
+var builtinScripts = map[string]bool{
+	"json.lua": true, // json 模块
+}
+
 func NewWithBehaviorTree(path string, bt *behavior.Tree, name, batch string, idx int32, globalScript string) *Bot {
 
 	bb := &behavior.Blackboard{
@@ -134,6 +141,8 @@ func NewWithBehaviorTree(path string, bt *behavior.Tree, name, batch string, idx
 
 	rand.Seed(time.Now().UnixNano())
 
+	bot.SetHttpOptions(script.HttpOptions{})
+
 	// 加载预定义全局脚本文件
 	if globalScript != "" {
 		pool.DoString(bot.bs.L, globalScript)
@@ -142,6 +151,10 @@ func NewWithBehaviorTree(path string, bt *behavior.Tree, name, batch string, idx
 	// 这里要对script目录进行一次检查，将lua脚本都载入进来
 	preScripts := utils.GetDirectoryFiels(path, ".lua")
 	for _, v := range preScripts {
+		if builtinScripts[v] {
+			continue
+		}
+
 		err := pool.DoFile(bot.bs.L, path+v)
 		if err != nil {
 			fmt.Println("err", err.Error())
@@ -165,28 +178,101 @@ func NewWithBehaviorTree(path string, bt *behavior.Tree, name, batch string, idx
 	return bot
 }
 
-func (b *Bot) loopThread(doneCh chan<- string, errch chan<- ErrInfo) {
+// SetFeeder 为 bot 绑定 batch 的数据集，并将分配到的第一行数据写入 meta.Data
+// 之后可以在脚本中通过 feeder.next() 继续获取数据
+func (b *Bot) SetFeeder(f *script.Feeder) {
+	b.bs.FeederMod.SetFeeder(f)
 
-	for {
-		state, end := b.tick.Do()
-		if end {
-			doneCh <- b.id
-			goto ext
-		}
+	row, err := b.bs.FeederMod.Row(b.bs.L)
+	if err != nil {
+		fmt.Println("bot", b.id, "feeder", f.Name, "err", err.Error())
+		return
+	}
 
-		if state == behavior.Break || state == behavior.Error {
-			errch <- ErrInfo{
-				ID:  b.id,
-				Err: nil,
-			}
-			goto ext
+	meta, ok := b.bs.L.GetGlobal("meta").(*lua.LTable)
+	if ok {
+		meta.RawSetString("Data", row)
+	}
+}
+
+// SetSeed 设置随机节点使用的随机数种子（相同的种子得到相同的执行路径
+func (b *Bot) SetSeed(seed int64) {
+	b.tick.SetSeed(seed)
+}
+
+// SetThinkTime 设置等待节点的等待时间倍率
+func (b *Bot) SetThinkTime(m float64) {
+	b.tick.SetThinkTime(m)
+}
+
+// SetHttpOptions 设置 http 配置（行为树根节点中设置了 http 配置时优先使用行为树中的
+func (b *Bot) SetHttpOptions(opts script.HttpOptions) {
+	var err error
+	if inner, ok := b.bt.HttpOptions(); ok {
+		opts, err = script.ParseHttpOptionsXML(inner)
+	}
+	if err == nil {
+		err = b.bs.HttpMod.Configure(opts)
+	}
+
+	if err != nil {
+		fmt.Println("http configure err", err.Error())
+		b.preloadErr = fmt.Sprintf("http configure err : %v", err.Error())
+	}
+}
+
+// SetShared 为 bot 绑定 batch 内共享的数据（shared 模块
+func (b *Bot) SetShared(s *script.SharedStore) {
+	b.bs.SharedMod.SetStore(s)
+}
+
+// step 执行一次 tick，bot 结束时通知 batch 并返回 true
+func (b *Bot) step(doneCh chan<- string, errch chan<- ErrInfo) bool {
+	state, end := b.tick.Do()
+
+	// 行为树以失败结束时同样作为错误
+	// 先释放 state 再通知 batch，batch 读取的是关闭时取出的报告
+	if state == behavior.Break || state == behavior.Error {
+		b.close()
+		errch <- ErrInfo{
+			ID:  b.id,
+			Err: nil,
 		}
+		return true
+	}
 
-		time.Sleep(time.Millisecond * 10)
+	if end {
+		b.close()
+		doneCh <- b.id
+		return true
 	}
 
-ext:
-	b.close()
+	return false
+}
+
+func (b *Bot) loopThread(doneCh chan<- string, errch chan<- ErrInfo) {
+	for !b.step(doneCh, errch) {
+		b.sleep()
+	}
+}
+
+// 节点都可以立即执行时 tick 的间隔（避免在循环中轮询的行为树占满 CPU
+const minTickInterval = time.Millisecond
+
+// sleep 休眠到下一个需要执行的节点到期，或者有事件触发（WaitForEventNode
+func (b *Bot) sleep() {
+	d := time.Until(b.tick.Next())
+	if d < minTickInterval {
+		d = minTickInterval
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-b.bs.Events.Wake():
+	}
 }
 
 func (b *Bot) RunByThread(doneCh chan<- string, errch chan<- ErrInfo) {
@@ -205,24 +291,47 @@ func (b *Bot) RunByBlock() error {
 
 	for {
 		state, end := b.tick.Do()
+		if state == behavior.Break || (end && state == behavior.Error) {
+			return behavior.ErrorNodeHaveErr
+		}
+
 		if end {
 			return nil
 		}
 
-		if state == behavior.Break || state == behavior.Exit {
+		if state == behavior.Exit {
 			return behavior.ErrorNodeHaveErr
 		}
 
-		time.Sleep(time.Millisecond * 10)
+		b.sleep()
 	}
 
 }
 
 func (b *Bot) GetReport() []script.Report {
+	if b.bs == nil {
+		return b.report
+	}
 	return b.bs.HttpMod.GetReport()
 }
 
+// GetNodeStats 获取节点的运行统计（重试、超时次数
+func (b *Bot) GetNodeStats() []behavior.NodeStat {
+	return b.tick.NodeStats()
+}
+
+// Close 释放 bot 的 lua state（调试、阻塞模式的 bot 不再使用时调用
+func (b *Bot) Close() {
+	b.close()
+}
+
 func (b *Bot) close() {
+	if b.bs == nil {
+		return
+	}
+
+	// state 放回池中后会被其他 bot 复用，报告需要先取出
+	b.report = b.bs.HttpMod.GetReport()
 
 	if b.bt.GetMode() == behavior.Thread {
 		b.bs.L.DoString(`
@@ -232,6 +341,7 @@ func (b *Bot) close() {
 	} else {
 		pool.FreeState(b.bs)
 	}
+	b.bs = nil
 
 }
 
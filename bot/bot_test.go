@@ -282,6 +282,21 @@ func TestBlockMode(t *testing.T) {
 	assert.Equal(t, err, nil)
 }
 
+func TestBuiltinScripts(t *testing.T) {
+	dir := t.TempDir() + "/"
+	os.WriteFile(dir+"json.lua", []byte(`error("json.lua must not be loaded")`), 0644)
+	os.WriteFile(dir+"user.lua", []byte(`user_loaded = true`), 0644)
+
+	tree, err := behavior.Load([]byte(compose), behavior.Block)
+	assert.Equal(t, err, nil)
+
+	bot := NewWithBehaviorTree(dir, tree, "test", "", 1, "")
+	defer bot.Close()
+
+	assert.Equal(t, bot.preloadErr, "")
+	assert.Equal(t, bot.bs.L.DoString(`assert(user_loaded and json.null ~= nil)`), nil)
+}
+
 /*
 func TestRuning(t *testing.T) {
 	var tree *behavior.Tree
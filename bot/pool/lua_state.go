@@ -16,11 +16,25 @@ type BotState struct {
 	L         *lua.LState
 	HttpMod   *script.HttpModule
 	TCPMod    *script.TCPModule
+	UDPMod    *script.UDPModule
 	protoMod  *script.ProtoModule
 	utilsMod  *script.UtilsModule
 	base64Mod *script.Base64Module
 	mgoMod    *script.MgoModule
+	redisMod  *script.RedisModule
+	sqlMod    *script.SQLModule
+	mqMod     *script.MQModule
+	authMod   *script.AuthModule
 	md5Mod    *script.MD5Module
+	cryptoMod *script.CryptoModule
+	jsonMod   *script.JSONModule
+	msgpkMod  *script.MsgpackModule
+	compMod   *script.CompressModule
+	FeederMod *script.FeederModule
+	SharedMod *script.SharedModule
+	eventMod  *script.EventModule
+
+	Events *script.Events // bot 的事件（WaitForEventNode
 }
 
 func (pl *lStatePool) Get() *BotState {
@@ -43,30 +57,76 @@ func _new_state() *BotState {
 		L:         lua.NewState(),
 		HttpMod:   script.NewHttpModule(),
 		TCPMod:    script.NewTCPModule(),
+		UDPMod:    script.NewUDPModule(),
 		protoMod:  &script.ProtoModule{},
 		utilsMod:  &script.UtilsModule{},
 		base64Mod: &script.Base64Module{},
 		mgoMod:    &script.MgoModule{},
+		redisMod:  script.NewRedisModule(),
+		sqlMod:    script.NewSQLModule(),
+		mqMod:     script.NewMQModule(),
 		md5Mod:    &script.MD5Module{},
+		cryptoMod: &script.CryptoModule{},
+		jsonMod:   &script.JSONModule{},
+		msgpkMod:  &script.MsgpackModule{},
+		compMod:   &script.CompressModule{},
+		FeederMod: script.NewFeederModule(),
+		SharedMod: script.NewSharedModule(),
+		Events:    script.NewEvents(),
 	}
+	b.authMod = script.NewAuthModule(b.HttpMod)
+	b.eventMod = script.NewEventModule(b.Events)
+	b.TCPMod.SetEvents(b.Events)
+	b.SharedMod.SetEvents(b.Events)
 
 	b.L.PreloadModule("proto", b.protoMod.Loader)
 	b.L.PreloadModule("http", b.HttpMod.Loader)
 	b.L.PreloadModule("tcpconn", b.TCPMod.Loader)
+	b.L.PreloadModule("udpconn", b.UDPMod.Loader)
 	b.L.PreloadModule("utils", b.utilsMod.Loader)
 	b.L.PreloadModule("base64", b.base64Mod.Loader)
 	b.L.PreloadModule("mgo", b.mgoMod.Loader)
+	b.L.PreloadModule("redis", b.redisMod.Loader)
+	b.L.PreloadModule("sql", b.sqlMod.Loader)
+	b.L.PreloadModule("mq", b.mqMod.Loader)
 	b.L.PreloadModule("md5", b.md5Mod.Loader)
+	b.L.PreloadModule("crypto", b.cryptoMod.Loader)
+	b.L.PreloadModule("json", b.jsonMod.Loader)
+	b.L.PreloadModule("msgpack", b.msgpkMod.Loader)
+	b.L.PreloadModule("compress", b.compMod.Loader)
+	b.L.PreloadModule("auth", b.authMod.Loader)
+	b.L.PreloadModule("feeder", b.FeederMod.Loader)
+	b.L.PreloadModule("shared", b.SharedMod.Loader)
+	b.L.PreloadModule("event", b.eventMod.Loader)
+
+	// 兼容直接使用全局 json 的脚本（之前由 script/json.lua 提供
+	b.L.DoString(`json = require("json")`)
 
 	return b
 }
 
 func (pl *lStatePool) Put(bs *BotState) {
+	bs.reset()
+
 	pl.m.Lock()
 	defer pl.m.Unlock()
 	pl.saved = append(pl.saved, bs)
 }
 
+// reset 清理 bot 在模块中留下的会话状态，避免被下一个复用 state 的 bot 继承
+func (b *BotState) reset() {
+	b.HttpMod.Reset()
+	b.TCPMod.Reset()
+	b.UDPMod.Reset()
+	b.redisMod.Reset()
+	b.sqlMod.Reset()
+	b.mqMod.Reset()
+	b.authMod.Reset()
+	b.FeederMod.SetFeeder(nil)
+	b.SharedMod.SetStore(nil)
+	b.Events.Reset()
+}
+
 func (pl *lStatePool) Shutdown() {
 	for _, bs := range pl.saved {
 		bs.L.Close()
@@ -86,6 +146,7 @@ func NewState() *BotState {
 }
 
 func FreeState(state *BotState) {
+	state.reset()
 	state.L.Close()
 }
 
@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// reuse 在 state 中执行 open 后放回池中，再次取出同一个 state 执行 check
+func reuse(t *testing.T, open string, check string) string {
+	bs := GetState()
+	assert.Equal(t, bs.L.DoString(open), nil)
+	PutState(bs)
+
+	reused := GetState()
+	defer PutState(reused)
+	assert.Equal(t, reused, bs)
+
+	assert.Equal(t, reused.L.DoString(check), nil)
+	return lua.LVAsString(reused.L.GetGlobal("ret"))
+}
+
+// tcpServer 等待一个连接，连接被关闭时关闭返回的 channel
+func tcpServer(t *testing.T) (string, chan struct{}) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, err, nil)
+	t.Cleanup(func() { ln.Close() })
+
+	closed := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 16)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port, closed
+}
+
+func waitClosed(t *testing.T, closed chan struct{}) {
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("tcp conn is not closed")
+	}
+}
+
+func TestResetTCP(t *testing.T) {
+	port, closed := tcpServer(t)
+	ret := reuse(t, `
+		local conn = require("tcpconn")
+		assert(conn.dail("127.0.0.1", "`+port+`") == "succ")
+	`, `
+		local conn = require("tcpconn")
+		ret = conn.write("hello")
+	`)
+	assert.Equal(t, ret, "not connected")
+	waitClosed(t, closed)
+}
+
+func TestFreeState(t *testing.T) {
+	port, closed := tcpServer(t)
+
+	bs := NewState()
+	err := bs.L.DoString(`
+		local conn = require("tcpconn")
+		assert(conn.dail("127.0.0.1", "` + port + `") == "succ")
+	`)
+	assert.Equal(t, err, nil)
+
+	FreeState(bs)
+	waitClosed(t, closed)
+}
+
+func TestResetUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Equal(t, err, nil)
+	defer pc.Close()
+
+	_, port, _ := net.SplitHostPort(pc.LocalAddr().String())
+	ret := reuse(t, `
+		local conn = require("udpconn")
+		assert(conn.dail("127.0.0.1", "`+port+`") == "succ")
+	`, `
+		local conn = require("udpconn")
+		ret = conn.write("hello")
+	`)
+	assert.Equal(t, ret, "not connected")
+}
+
+func TestResetRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	ret := reuse(t, `
+		local redis = require("redis")
+		assert(redis.conn("redis://`+mr.Addr()+`/0") == "succ")
+	`, `
+		local redis = require("redis")
+		_, ret = redis.get("key")
+	`)
+	assert.Equal(t, ret, "redis not connected")
+	assert.Eventually(t, func() bool { return mr.CurrentConnectionCount() == 0 }, time.Second, time.Millisecond*10)
+}
+
+func TestResetSQL(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "reset.db")
+
+	ret := reuse(t, `
+		local sql = require("sql")
+		assert(sql.open("sqlite", "`+dsn+`") == "succ")
+		sql.exec("create table users (name text)")
+		sql.begin()
+		sql.exec("insert into users (name) values (?)", "bot")
+	`, `
+		local sql = require("sql")
+		_, ret = sql.query("select count(*) as n from users")
+		assert(sql.open("sqlite", "`+dsn+`") == "succ")
+		local rows = sql.query("select count(*) as n from users")
+		assert(rows[1].n == 0, "uncommitted insert is not rolled back")
+		sql.close()
+	`)
+	assert.Equal(t, ret, "sql not opened")
+}
+
+func TestResetMQ(t *testing.T) {
+	ret := reuse(t, `
+		local mq = require("mq")
+		assert(mq.conn("memory", "reset") == "succ")
+		assert(mq.subscribe("orders") == "succ")
+	`, `
+		local mq = require("mq")
+		ret = mq.publish("orders", "1")
+	`)
+	assert.Equal(t, ret, "mq not connected")
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"container/heap"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultWorkers 默认的 worker 数量
+// 等待其他 bot 的调用（shared.pop、shared.barrier）不会阻塞，bot 通过 WaitForEventNode 等待事件并交还 worker
+// 脚本中的阻塞调用（http 请求等）会占用 worker，请求的延迟较高时需要增加 worker 的数量
+func DefaultWorkers() int {
+	return runtime.NumCPU() * 8
+}
+
+type schedEntry struct {
+	bot    *Bot
+	doneCh chan<- string
+	errch  chan<- ErrInfo
+
+	at    time.Time // 下一次执行的时间
+	index int       // 在队列中的位置，-1 表示正在执行
+	woken bool      // 执行期间有事件触发，执行结束后立即重新执行
+}
+
+// schedQueue 按照下一次执行的时间排序的优先队列
+type schedQueue []*schedEntry
+
+func (q schedQueue) Len() int           { return len(q) }
+func (q schedQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+func (q schedQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *schedQueue) Push(x interface{}) {
+	e := x.(*schedEntry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *schedQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*q = old[:n-1]
+	return e
+}
+
+// Scheduler 使用固定数量的 worker 执行 bot（代替每个 bot 一个 goroutine
+// bot 按照下一次需要执行的时间（Tick.Next）放入优先队列，有事件触发时（WaitForEventNode）立即执行
+type Scheduler struct {
+	sync.Mutex
+	queue schedQueue
+
+	ready  chan *schedEntry
+	notify chan struct{} // 队列头部发生变化时唤醒 dispatch
+	exit   chan struct{}
+	once   sync.Once
+}
+
+func NewScheduler(workers int) *Scheduler {
+	if workers <= 0 {
+		workers = DefaultWorkers()
+	}
+
+	s := &Scheduler{
+		ready:  make(chan *schedEntry),
+		notify: make(chan struct{}, 1),
+		exit:   make(chan struct{}),
+	}
+
+	go s.dispatch()
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	return s
+}
+
+// Add 将 bot 加入调度，bot 结束时通过 doneCh / errch 通知（与 RunByThread 一致
+func (s *Scheduler) Add(b *Bot, doneCh chan<- string, errch chan<- ErrInfo) {
+	e := &schedEntry{
+		bot:    b,
+		doneCh: doneCh,
+		errch:  errch,
+		at:     time.Now(),
+	}
+
+	// 放入队列之后再设置回调（wake 使用 e.index，还没有放入队列时会修改到其他的 bot
+	s.Lock()
+	heap.Push(&s.queue, e)
+	b.bs.Events.SetNotify(func() { s.wake(e) })
+	s.Unlock()
+	s.wakeup()
+}
+
+// Stop 停止调度（还没有结束的 bot 不会再被执行
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.exit)
+	})
+}
+
+func (s *Scheduler) wakeup() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// wake bot 有事件触发时调用
+func (s *Scheduler) wake(e *schedEntry) {
+	s.Lock()
+	if e.index >= 0 {
+		e.at = time.Now()
+		heap.Fix(&s.queue, e.index)
+	} else {
+		e.woken = true
+	}
+	s.Unlock()
+	s.wakeup()
+}
+
+func (s *Scheduler) dispatch() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		wait := time.Hour
+
+		s.Lock()
+		for len(s.queue) > 0 {
+			now := time.Now()
+			if s.queue[0].at.After(now) {
+				wait = s.queue[0].at.Sub(now)
+				break
+			}
+
+			e := heap.Pop(&s.queue).(*schedEntry)
+			s.Unlock()
+
+			select {
+			case s.ready <- e:
+			case <-s.exit:
+				return
+			}
+
+			s.Lock()
+		}
+		s.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-s.notify:
+		case <-s.exit:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) work() {
+	for {
+		select {
+		case e := <-s.ready:
+			s.run(e)
+		case <-s.exit:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) run(e *schedEntry) {
+	if e.bot.step(e.doneCh, e.errch) {
+		return
+	}
+
+	next := e.bot.tick.Next()
+	if min := time.Now().Add(minTickInterval); next.Before(min) {
+		next = min
+	}
+
+	s.Lock()
+	if e.woken {
+		e.woken = false
+		next = time.Now()
+	}
+	e.at = next
+	heap.Push(&s.queue, e)
+	s.Unlock()
+	s.wakeup()
+}
@@ -0,0 +1,250 @@
+package bot
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pojol/gobot/bot/behavior"
+	script "github.com/pojol/gobot/script/module"
+	"github.com/stretchr/testify/assert"
+)
+
+// pacing 模拟用户的行为，每次操作之间等待 20ms
+var pacing = `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>loop</id>
+    <ty>LoopNode</ty>
+    <loop>10</loop>
+    <children>
+      <id>wait</id>
+      <ty>WaitNode</ty>
+      <wait>20</wait>
+      <children>
+        <id>action</id>
+        <ty>ActionNode</ty>
+        <code>
+function execute()
+  meta.Count = (meta.Count or 0) + 1
+end
+        </code>
+      </children>
+    </children>
+  </children>
+</behavior>`
+
+var waitEvent = `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>timer</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  require("event").after("ready", 0.05)
+end
+      </code>
+    </children>
+    <children>
+      <id>wait</id>
+      <ty>WaitForEventNode</ty>
+      <event>ready</event>
+      <timeout>10000</timeout>
+    </children>
+  </children>
+</behavior>`
+
+// barrier 人数没有到齐时通过 shared:start 事件等待，不会占用 worker
+var barrier = `
+<behavior>
+  <id>root</id>
+  <ty>RootNode</ty>
+  <children>
+    <id>seq</id>
+    <ty>SequenceNode</ty>
+    <children>
+      <id>sel</id>
+      <ty>SelectorNode</ty>
+      <children>
+        <id>arrive</id>
+        <ty>ConditionNode</ty>
+        <code>
+function execute()
+  return require("shared").barrier("start", 10) == "succ"
+end
+        </code>
+      </children>
+      <children>
+        <id>wait</id>
+        <ty>WaitForEventNode</ty>
+        <event>shared:start</event>
+        <timeout>5000</timeout>
+      </children>
+    </children>
+    <children>
+      <id>pass</id>
+      <ty>ActionNode</ty>
+      <code>
+function execute()
+  require("shared").incr("pass")
+end
+      </code>
+    </children>
+  </children>
+</behavior>`
+
+func newBots(t testing.TB, tree string, n int) []*Bot {
+	bots := make([]*Bot, 0, n)
+	for i := 0; i < n; i++ {
+		bt, err := behavior.Load([]byte(tree), behavior.Thread)
+		assert.Equal(t, err, nil)
+
+		bots = append(bots, NewWithBehaviorTree("../script/", bt, "bench", "", int32(i+1), ""))
+	}
+	return bots
+}
+
+// wait 等待所有的 bot 结束，返回出错的 bot 数量
+func waitBots(n int, doneCh chan string, errch chan ErrInfo) int {
+	errs := 0
+	for i := 0; i < n; i++ {
+		select {
+		case <-doneCh:
+		case <-errch:
+			errs++
+		}
+	}
+	return errs
+}
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler(4)
+	defer s.Stop()
+
+	doneCh := make(chan string)
+	errch := make(chan ErrInfo)
+
+	begin := time.Now()
+	for _, b := range newBots(t, pacing, 100) {
+		s.Add(b, doneCh, errch)
+	}
+	assert.Equal(t, waitBots(100, doneCh, errch), 0)
+	assert.GreaterOrEqual(t, time.Since(begin), 200*time.Millisecond)
+	assert.Less(t, time.Since(begin), 2*time.Second)
+
+	// 等待事件的 bot 在事件触发时被唤醒
+	begin = time.Now()
+	for _, b := range newBots(t, waitEvent, 10) {
+		s.Add(b, doneCh, errch)
+	}
+	assert.Equal(t, waitBots(10, doneCh, errch), 0)
+	assert.Less(t, time.Since(begin), time.Second)
+}
+
+func TestSchedulerBarrier(t *testing.T) {
+	// barrier 的人数多于 worker 的数量
+	s := NewScheduler(2)
+	defer s.Stop()
+
+	doneCh := make(chan string)
+	errch := make(chan ErrInfo)
+	store := script.NewSharedStore()
+
+	begin := time.Now()
+	for _, b := range newBots(t, barrier, 10) {
+		b.SetShared(store)
+		s.Add(b, doneCh, errch)
+	}
+	assert.Equal(t, waitBots(10, doneCh, errch), 0)
+	assert.Less(t, time.Since(begin), 2*time.Second)
+	assert.Equal(t, store.Get("pass"), int64(10))
+}
+
+func cpuTime() time.Duration {
+	var usage syscall.Rusage
+	syscall.Getrusage(syscall.RUSAGE_SELF, &usage)
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+func memInuse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse + m.StackInuse
+}
+
+// pacingTime pacing 中用户操作的总时间，用于计算每个 cpu 核可以承载的 bot 数量
+const pacingTime = 10 * 20 * time.Millisecond
+
+// benchBots 运行 n 个 bot，报告每个 bot 消耗的 cpu 时间（cpu-ms/bot）、每个 cpu 核可以承载的 bot 数量（bots/core）
+// 以及每个 bot 占用的内存（KB/bot
+func benchBots(b *testing.B, n int, run func(*Bot, chan string, chan ErrInfo)) {
+	var cpu time.Duration
+	var mem uint64
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		runtime.GC()
+		base := memInuse()
+		bots := newBots(b, pacing, n)
+		doneCh := make(chan string)
+		errch := make(chan ErrInfo)
+		b.StartTimer()
+
+		cpubegin := cpuTime()
+		for _, bot := range bots {
+			run(bot, doneCh, errch)
+		}
+
+		// 所有的 bot 都在运行中时统计内存
+		time.Sleep(50 * time.Millisecond)
+		if inuse := memInuse(); inuse > base {
+			mem += (inuse - base) / uint64(n)
+		}
+
+		if errs := waitBots(n, doneCh, errch); errs != 0 {
+			b.Fatalf("%v bots failed", errs)
+		}
+		cpu += cpuTime() - cpubegin
+	}
+
+	perbot := cpu / time.Duration(b.N*n)
+	b.ReportMetric(float64(perbot)/float64(time.Millisecond), "cpu-ms/bot")
+	b.ReportMetric(float64(pacingTime)/float64(perbot), "bots/core")
+	b.ReportMetric(float64(mem)/1024/float64(b.N), "KB/bot")
+}
+
+// BenchmarkPolling 之前的实现，每个 bot 一个 goroutine，每 10ms tick 一次
+func BenchmarkPolling(b *testing.B) {
+	benchBots(b, 1000, func(bot *Bot, doneCh chan string, errch chan ErrInfo) {
+		go func() {
+			for !bot.step(doneCh, errch) {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+	})
+}
+
+// BenchmarkGoroutinePerBot 每个 bot 一个 goroutine，休眠到下一个节点到期
+func BenchmarkGoroutinePerBot(b *testing.B) {
+	benchBots(b, 1000, func(bot *Bot, doneCh chan string, errch chan ErrInfo) {
+		bot.RunByThread(doneCh, errch)
+	})
+}
+
+// BenchmarkScheduler 固定数量的 worker 执行所有的 bot
+func BenchmarkScheduler(b *testing.B) {
+	s := NewScheduler(0)
+	defer s.Stop()
+
+	benchBots(b, 1000, func(bot *Bot, doneCh chan string, errch chan ErrInfo) {
+		s.Add(bot, doneCh, errch)
+	})
+}
@@ -22,6 +22,7 @@ type Cache struct {
 	conf     *Conf
 	behavior *Behavior
 	prefab   *Prefab
+	dataset  *Dataset
 	report   *Report
 	task     *Task
 
@@ -38,6 +39,10 @@ func GetPrefab() *Prefab {
 	return db.prefab
 }
 
+func GetDataset() *Dataset {
+	return db.dataset
+}
+
 func GetReport() *Report {
 	return db.report
 }
@@ -99,6 +104,7 @@ func Init(NoDBMode bool) (*Cache, error) {
 		mysqlptr: sqlptr,
 		conf:     CreateConfig(sqlptr),
 		prefab:   CreatePrefab(sqlptr),
+		dataset:  CreateDataset(sqlptr),
 		behavior: CreateBehavior(sqlptr),
 		report:   CreateReport(sqlptr),
 		task:     CreateTask(sqlptr),
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -10,11 +11,13 @@ import (
 
 type ConfTable struct {
 	gorm.Model
-	Name         string `json:"name" gorm:"<-"`
-	ChannelSize  int    `json:"channelsize" gorm:"<-"`
-	ReportSize   int    `json:"reportsize" gorm:"<-"`
-	GlobalCode   []byte `json:"globalcode" gorm:"<-"`
-	EnqueneDelay int    `json:"enquenedelay" gorm:"<-"`
+	Name         string  `json:"name" gorm:"<-"`
+	ChannelSize  int     `json:"channelsize" gorm:"<-"`
+	ReportSize   int     `json:"reportsize" gorm:"<-"`
+	GlobalCode   []byte  `json:"globalcode" gorm:"<-"`
+	EnqueneDelay int     `json:"enquenedelay" gorm:"<-"`
+	HttpOptions  []byte  `json:"httpoptions" gorm:"<-"`         // json 格式的 http 配置（由 server 检查后保存
+	ThinkTime    float64 `json:"thinktime" gorm:"<-;default:1"` // 等待节点的等待时间倍率（0 为功能测试，1 为压力测试
 }
 
 type Conf struct {
@@ -39,6 +42,7 @@ func CreateConfig(mysqlptr *gorm.DB) *Conf {
 			ChannelSize:  512,
 			ReportSize:   100,
 			EnqueneDelay: 1,
+			ThinkTime:    1,
 			GlobalCode: []byte(`
 --[[
 	Global constant area, users can define some constants here; it is easy to call in other scripts
@@ -120,6 +124,24 @@ func (c *Conf) UpdateEnqueneDelay(d int) {
 	c.update("enquene_delay", d)
 }
 
+func (c *Conf) UpdateThinkTime(m float64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if m < 0 {
+		fmt.Println("wrong input", m)
+		return fmt.Errorf("wrong input %v", m)
+	}
+
+	_, err := c.Get()
+	if err != nil {
+		return err
+	}
+
+	c.update("think_time", m)
+	return nil
+}
+
 func (c *Conf) UpdateGlobalDefine(code []byte) error {
 	c.Lock()
 	defer c.Unlock()
@@ -143,3 +165,20 @@ func (c *Conf) UpdateGlobalDefine(code []byte) error {
 	c.update("global_code", code)
 	return nil
 }
+
+func (c *Conf) UpdateHttpOptions(byt []byte) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(byt) != 0 && !json.Valid(byt) {
+		return fmt.Errorf("http options is not json %v", string(byt))
+	}
+
+	_, err := c.Get()
+	if err != nil {
+		return err
+	}
+
+	c.update("http_options", byt)
+	return nil
+}
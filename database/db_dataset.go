@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// DatasetTable 上传的数据集文件（csv / json，在创建 batch 时分配给 bot
+type DatasetTable struct {
+	gorm.Model
+	Name string `gorm:"<-"`
+	Data []byte `gorm:"<-"`
+}
+
+type Dataset struct {
+	db *gorm.DB
+	sync.Mutex
+}
+
+func CreateDataset(mysqlptr *gorm.DB) *Dataset {
+	d := &Dataset{
+		db: mysqlptr,
+	}
+
+	err := d.db.AutoMigrate(&DatasetTable{})
+	if err != nil {
+		fmt.Println("migrate err", err.Error())
+	}
+
+	return d
+}
+
+func (d *Dataset) List() ([]DatasetTable, error) {
+	lst := []DatasetTable{}
+
+	res := d.db.Find(&lst)
+
+	return lst, res.Error
+}
+
+func (d *Dataset) Find(name string) (DatasetTable, error) {
+	t := DatasetTable{}
+
+	res := d.db.Where("name = ?", name).First(&t)
+
+	return t, res.Error
+}
+
+func (d *Dataset) Upset(name string, dat []byte) {
+	t, err := d.Find(name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			d.db.Model(&DatasetTable{}).Create(&DatasetTable{
+				Name: name,
+				Data: dat,
+			})
+		} else {
+			fmt.Println("dataset upset err", err.Error())
+		}
+	} else {
+		t.Data = dat
+		res := d.db.Model(&DatasetTable{}).Where("name = ?", name).Updates(&t)
+		if res.Error != nil {
+			fmt.Println("dataset upset update err", res.Error)
+		}
+	}
+}
+
+func (d *Dataset) Rmv(name string) error {
+
+	info := DatasetTable{}
+	res := d.db.Where("name = ?", name).Delete(&info)
+
+	return res.Error
+}
@@ -18,6 +18,16 @@ type ApiDetail struct {
 
 	ReqSize int64
 	ResSize int64
+
+	StreamNum     int   // 收到过消息的流式请求数量
+	FirstEventNum int64 // 流式请求收到第一条消息的耗时总和
+}
+
+// NodeDetail 行为树节点的统计（重试、超时次数
+type NodeDetail struct {
+	Ty      string
+	Retry   int
+	Timeout int
 }
 
 type ReportDetail struct {
@@ -31,7 +41,8 @@ type ReportDetail struct {
 
 	BeginTime time.Time
 
-	UrlMap map[string]*ApiDetail
+	UrlMap  map[string]*ApiDetail
+	NodeMap map[string]*NodeDetail
 }
 
 ////////////////////////////////////////////////////////
@@ -44,10 +55,29 @@ type ReportApiInfo struct {
 
 	ReqSize int64
 	ResSize int64
+
+	FirstEventNum int64 `json:",omitempty"` // 流式请求收到第一条消息的平均耗时
 }
 
 type ReportApiArr []ReportApiInfo
 
+type ReportNodeInfo struct {
+	ID      string
+	Ty      string
+	Retry   int
+	Timeout int
+}
+
+type ReportNodeArr []ReportNodeInfo
+
+func (p ReportNodeArr) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *ReportNodeArr) Scan(data interface{}) error {
+	return json.Unmarshal(data.([]byte), &p)
+}
+
 func (p ReportApiArr) Value() (driver.Value, error) {
 	return json.Marshal(p)
 }
@@ -66,7 +96,8 @@ type ReportTable struct {
 	Tps        int
 	Dura       string
 	BeginTime  int64
-	ApiInfoLst ReportApiArr `gorm:"column:childrens;type:longtext"`
+	ApiInfoLst ReportApiArr  `gorm:"column:childrens;type:longtext"`
+	NodeLst    ReportNodeArr `gorm:"column:nodes;type:longtext"`
 }
 
 type Report struct {
@@ -104,14 +135,31 @@ func (r *Report) Append(info ReportDetail) error {
 		fmtapi := ""
 		if err == nil {
 			fmtapi = u.Path
+			if u.Fragment != "" {
+				// graphql 等按照操作名统计的接口
+				fmtapi += "#" + u.Fragment
+			}
 		}
-		ri.ApiInfoLst = append(ri.ApiInfoLst, ReportApiInfo{
+		apiinfo := ReportApiInfo{
 			Api:        fmtapi,
 			ReqNum:     detail.ReqNum,
 			ConsumeNum: int64(detail.AvgNum / int64(detail.ReqNum)),
 			ReqSize:    detail.ReqSize,
 			ResSize:    detail.ResSize,
 			ErrNum:     detail.ErrNum,
+		}
+		if detail.StreamNum != 0 {
+			apiinfo.FirstEventNum = detail.FirstEventNum / int64(detail.StreamNum)
+		}
+		ri.ApiInfoLst = append(ri.ApiInfoLst, apiinfo)
+	}
+
+	for id, detail := range info.NodeMap {
+		ri.NodeLst = append(ri.NodeLst, ReportNodeInfo{
+			ID:      id,
+			Ty:      detail.Ty,
+			Retry:   detail.Retry,
+			Timeout: detail.Timeout,
 		})
 	}
 
@@ -13,6 +13,10 @@ type TaskTable struct {
 	Name        string `gorm:"<-"`
 	TotalNumber int32  `gorm:"<-"`
 	CurNumber   int32  `gorm:"<-"`
+	Dataset     string `gorm:"<-"`
+	Feed        string `gorm:"<-"`
+	Seed        int64  `gorm:"<-"`
+	FeedOffset  int32  `gorm:"<-"` // 数据集已经分配的行数
 }
 
 type Task struct {
@@ -50,7 +54,7 @@ func (b *Task) Rmv(id string) error {
 	return b.db.Model(&tt).Where("id = ?", id).Delete(&tt).Error
 }
 
-func (b *Task) Update(id string, cur int32) error {
+func (b *Task) Update(id string, cur int32, offset int32) error {
 	var tt TaskTable
 	fmt.Println("update task", id, cur)
 	if id == "" || cur < 0 {
@@ -67,5 +71,8 @@ func (b *Task) Update(id string, cur int32) error {
 		return nil
 	}
 
-	return b.db.Model(&tt).Where("id = ?", id).Update("cur_number", cur).Error
+	return b.db.Model(&tt).Where("id = ?", id).Updates(map[string]interface{}{
+		"cur_number":  cur,
+		"feed_offset": offset,
+	}).Error
 }
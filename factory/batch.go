@@ -13,6 +13,7 @@ import (
 	"github.com/pojol/gobot/bot"
 	"github.com/pojol/gobot/bot/behavior"
 	"github.com/pojol/gobot/database"
+	script "github.com/pojol/gobot/script/module"
 	"github.com/pojol/gobot/utils"
 )
 
@@ -22,6 +23,7 @@ type BatchInfo struct {
 	Cur    int32
 	Max    int32
 	Errors int32
+	Seed   int64
 }
 
 type Batch struct {
@@ -33,12 +35,18 @@ type Batch struct {
 	BatchNum     int32
 	enqueneDelay int32
 	Errors       int32
+	Seed         int64 // batch 的随机数种子，bot 的种子为 Seed + bot 的序号
+	thinktime    float64
 
-	treeData     []byte
+	treeData     []byte // 展开了子树的行为树（behavior.Expand
 	path         string
 	globalScript string
+	feeder       *script.Feeder
+	shared       *script.SharedStore
+	httpOptions  script.HttpOptions // ConfTable 中的 http 配置
 
 	bots    map[string]*bot.Bot
+	sched   *bot.Scheduler
 	colorer *color.Color
 	rep     *database.ReportDetail
 
@@ -58,6 +66,11 @@ type BatchConfig struct {
 	globalScript  string
 	scriptPath    string
 	enqeueneDelay int32
+	feeder        *script.Feeder
+	seed          int64
+	thinktime     float64
+	workers       int
+	httpOptions   script.HttpOptions
 }
 
 func CreateBatch(name string, cur, total int32, tbyt []byte, cfg BatchConfig) *Batch {
@@ -71,9 +84,15 @@ func CreateBatch(name string, cur, total int32, tbyt []byte, cfg BatchConfig) *B
 		CurNum:       cur,
 		BatchNum:     cfg.batchsize,
 		TotalNum:     total,
+		Seed:         cfg.seed,
+		thinktime:    cfg.thinktime,
+		httpOptions:  cfg.httpOptions,
 		bwg:          utils.NewSizeWaitGroup(int(cfg.batchsize)),
 		exit:         utils.NewSwitch(),
 		treeData:     tbyt,
+		feeder:       cfg.feeder,
+		shared:       script.NewSharedStore(),
+		sched:        bot.NewScheduler(cfg.workers),
 		pipeline:     make(chan *bot.Bot, cfg.batchsize),
 		done:         make(chan interface{}, 1),
 		BatchDone:    make(chan interface{}, 1),
@@ -84,13 +103,20 @@ func CreateBatch(name string, cur, total int32, tbyt []byte, cfg BatchConfig) *B
 		bots:    make(map[string]*bot.Bot),
 	}
 
-	fmt.Println("create", total, "bot", "pipeline size", cfg.batchsize)
-	database.GetTask().New(database.TaskTable{
+	fmt.Println("create", total, "bot", "pipeline size", cfg.batchsize, "seed", b.Seed)
+	task := database.TaskTable{
 		ID:          b.ID,
 		Name:        name,
 		TotalNumber: b.TotalNum,
-		CurNumber:   0,
-	})
+		CurNumber:   b.CurNum,
+		Seed:        b.Seed,
+	}
+	if b.feeder != nil {
+		task.Dataset = b.feeder.Name
+		task.Feed = b.feeder.Strategy
+		task.FeedOffset = int32(b.feeder.Offset())
+	}
+	database.GetTask().New(task)
 
 	go b.loop()
 	b.run()
@@ -107,6 +133,7 @@ func (b *Batch) Info() BatchInfo {
 		Cur:    cur,
 		Max:    b.TotalNum,
 		Errors: atomic.LoadInt32(&b.Errors),
+		Seed:   b.Seed,
 	}
 }
 
@@ -134,13 +161,14 @@ func (b *Batch) loop() {
 		Name:      b.Name,
 		BeginTime: time.Now(),
 		UrlMap:    make(map[string]*database.ApiDetail),
+		NodeMap:   make(map[string]*database.NodeDetail),
 	}
 
 	for {
 		select {
 		case botptr := <-b.pipeline:
 			b.push(botptr)
-			botptr.RunByThread(b.botDoneCh, b.botErrCh)
+			b.sched.Add(botptr, b.botDoneCh, b.botErrCh)
 		case id := <-b.botDoneCh:
 			if _, ok := b.bots[id]; ok {
 				b.pushReport(b.rep, b.bots[id])
@@ -157,6 +185,7 @@ func (b *Batch) loop() {
 		}
 	}
 ext:
+	b.sched.Stop()
 	b.record()
 	b.exit.Done()
 	b.BatchDone <- 1
@@ -186,13 +215,29 @@ func (b *Batch) run() {
 				atomic.AddInt32(&b.cursorNum, 1)
 				b.bwg.Add()
 
-				tree, _ := behavior.Load(b.treeData, behavior.Thread)
-				b.pipeline <- bot.NewWithBehaviorTree(b.path, tree, b.Name, b.ID, atomic.LoadInt32(&b.cursorNum), b.globalScript)
+				tree, err := behavior.Load(b.treeData, behavior.Thread)
+				if err != nil {
+					// 引用的子树在 batch 运行期间被删除或者修改
+					fmt.Println("batch", b.ID, "load behavior err", err.Error())
+					atomic.AddInt32(&b.Errors, 1)
+					b.pop(strconv.Itoa(int(atomic.LoadInt32(&b.cursorNum))))
+					continue
+				}
+
+				botptr := bot.NewWithBehaviorTree(b.path, tree, b.Name, b.ID, atomic.LoadInt32(&b.cursorNum), b.globalScript)
+				botptr.SetShared(b.shared)
+				botptr.SetSeed(b.Seed + int64(atomic.LoadInt32(&b.cursorNum)))
+				botptr.SetThinkTime(b.thinktime)
+				botptr.SetHttpOptions(b.httpOptions)
+				if b.feeder != nil {
+					botptr.SetFeeder(b.feeder)
+				}
+				b.pipeline <- botptr
 				time.Sleep(time.Millisecond * time.Duration(b.enqueneDelay))
 			}
 
 			b.bwg.Wait()
-			database.GetTask().Update(b.ID, atomic.LoadInt32(&b.CurNum))
+			database.GetTask().Update(b.ID, atomic.LoadInt32(&b.CurNum), b.feedOffset())
 			fmt.Println("batch", b.ID, "end", atomic.LoadInt32(&b.CurNum), "=>", b.TotalNum)
 			if atomic.LoadInt32(&b.CurNum) >= b.TotalNum {
 				b.done <- 1
@@ -205,6 +250,13 @@ func (b *Batch) run() {
 
 }
 
+func (b *Batch) feedOffset() int32 {
+	if b.feeder == nil {
+		return 0
+	}
+	return int32(b.feeder.Offset())
+}
+
 func (b *Batch) Close() {
 
 }
@@ -223,12 +275,25 @@ func (b *Batch) pushReport(rep *database.ReportDetail, bot *bot.Bot) {
 		rep.UrlMap[v.Api].AvgNum += int64(v.Consume)
 		rep.UrlMap[v.Api].ReqSize += int64(v.ReqBody)
 		rep.UrlMap[v.Api].ResSize += int64(v.ResBody)
+		if v.Stream && v.FirstEvent >= 0 {
+			rep.UrlMap[v.Api].StreamNum++
+			rep.UrlMap[v.Api].FirstEventNum += int64(v.FirstEvent)
+		}
 		if v.Err != "" {
 			rep.ErrNum++
 			rep.UrlMap[v.Api].ErrNum++
 		}
 	}
 
+	for _, v := range bot.GetNodeStats() {
+		if _, ok := rep.NodeMap[v.ID]; !ok {
+			rep.NodeMap[v.ID] = &database.NodeDetail{Ty: v.Ty}
+		}
+
+		rep.NodeMap[v.ID].Retry += v.Retry
+		rep.NodeMap[v.ID].Timeout += v.Timeout
+	}
+
 }
 
 func (b *Batch) record() {
@@ -261,14 +326,33 @@ func (b *Batch) record() {
 		reqtotal += int64(v.ReqNum)
 
 		u, _ := url.Parse(sk)
+		api := u.Path
+		if u.Fragment != "" {
+			api += "#" + u.Fragment
+		}
 		if v.ErrNum != 0 {
-			b.colorer.Printf("%-40s %-15d %-18s %-18s %-10s\n", u.Path, v.ReqNum, avg, reqsize+" / "+ressize, utils.Red(succ))
+			b.colorer.Printf("%-40s %-15d %-18s %-18s %-10s\n", api, v.ReqNum, avg, reqsize+" / "+ressize, utils.Red(succ))
 		} else {
-			fmt.Printf("%-40s %-15d %-18s %-18s %-10s\n", u.Path, v.ReqNum, avg, reqsize+" / "+ressize, succ)
+			fmt.Printf("%-40s %-15d %-18s %-18s %-10s\n", api, v.ReqNum, avg, reqsize+" / "+ressize, succ)
 		}
 	}
 	fmt.Println("+--------------------------------------------------------------------------------------------------------+")
 
+	if len(b.rep.NodeMap) != 0 {
+		nodes := []string{}
+		for k := range b.rep.NodeMap {
+			nodes = append(nodes, k)
+		}
+		sort.Strings(nodes)
+
+		fmt.Printf("Node%-36s Type%-11s Retry%-13s Timeout\n", "", "", "")
+		for _, id := range nodes {
+			v := b.rep.NodeMap[id]
+			fmt.Printf("%-40s %-15s %-18d %-10d\n", id, v.Ty, v.Retry, v.Timeout)
+		}
+		fmt.Println("+--------------------------------------------------------------------------------------------------------+")
+	}
+
 	durations := int(time.Since(b.rep.BeginTime).Seconds())
 	if durations <= 0 {
 		durations = 1
@@ -8,13 +8,18 @@ import (
 	"github.com/pojol/gobot/bot"
 	"github.com/pojol/gobot/bot/behavior"
 	"github.com/pojol/gobot/database"
+	script "github.com/pojol/gobot/script/module"
 	"github.com/pojol/gobot/utils"
 )
 
 type TaskInfo struct {
-	Name string
-	Cur  int32
-	Num  int32
+	Name    string
+	Cur     int32
+	Num     int32
+	Dataset string // 分配给 bot 的数据集（为空时不使用
+	Feed    string // 数据集的分配策略
+	Seed    int64  // 随机数种子（为 0 时在创建 batch 时生成，相同的种子可以复现随机的执行路径
+	Offset  int32  // 数据集已经分配的行数（恢复任务时不会再分配这些数据
 }
 
 type Factory struct {
@@ -46,6 +51,17 @@ func Create(opts ...Option) (*Factory, error) {
 		opt(&p)
 	}
 
+	script.SetScriptPath(p.ScriptPath)
+
+	// SubtreeNode 引用的行为树从数据库中获取，修改被引用的行为树后对所有引用它的行为树生效
+	behavior.SetSubtreeLoader(func(name string) ([]byte, error) {
+		info, err := database.GetBehavior().Find(name)
+		if err != nil {
+			return nil, err
+		}
+		return info.File, nil
+	})
+
 	db, err := database.Init(p.NoDBMode)
 	if err != nil {
 		panic(err)
@@ -76,7 +92,15 @@ func (f *Factory) CheckTaskHistory() {
 	tasklst, _ := database.GetTask().List()
 	for _, task := range tasklst {
 		fmt.Println("recover task", task.Name, task.CurNumber, task.TotalNumber)
-		f.AddBatch(task.Name, task.CurNumber, task.TotalNumber)
+		f.AddTask(TaskInfo{
+			Name:    task.Name,
+			Cur:     task.CurNumber,
+			Num:     task.TotalNumber,
+			Dataset: task.Dataset,
+			Feed:    task.Feed,
+			Seed:    task.Seed,
+			Offset:  task.FeedOffset,
+		})
 
 		// 删除旧表
 		database.GetTask().Rmv(task.ID)
@@ -84,37 +108,100 @@ func (f *Factory) CheckTaskHistory() {
 }
 
 func (f *Factory) AddBatch(name string, cur, total int32) error {
+	return f.AddBatchWithDataset(name, cur, total, "", "")
+}
+
+// AddBatchWithDataset 创建 batch 并将数据集按照 feed 策略分配给 batch 中的 bot
+func (f *Factory) AddBatchWithDataset(name string, cur, total int32, dataset, feed string) error {
+	return f.AddTask(TaskInfo{
+		Name:    name,
+		Cur:     cur,
+		Num:     total,
+		Dataset: dataset,
+		Feed:    feed,
+	})
+}
+
+// AddTask 将 batch 加入到创建队列
+func (f *Factory) AddTask(task TaskInfo) error {
+
+	info, err := database.GetBehavior().Find(task.Name)
+	if err != nil {
+		return err
+	}
 
-	_, err := database.GetBehavior().Find(name)
+	// 检查引用的子树
+	_, err = behavior.Load(info.File, behavior.Thread)
 	if err != nil {
 		return err
 	}
 
-	f.pipelineCache = append(f.pipelineCache, TaskInfo{Name: name, Cur: cur, Num: total})
+	if task.Dataset != "" {
+		ds, err := database.GetDataset().Find(task.Dataset)
+		if err != nil {
+			return fmt.Errorf("can't find dataset %v", task.Dataset)
+		}
+
+		// 提前检查数据集和分配策略，避免在 taskLoop 中创建失败
+		_, err = script.NewFeeder(task.Dataset, ds.Data, task.Feed, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.pipelineCache = append(f.pipelineCache, task)
 	return nil
 }
 
-func (f *Factory) createBatch(name string, cur, num int32) *Batch {
+// createBatch 任务在队列中等待期间，行为树、数据集可能已经被删除
+func (f *Factory) createBatch(task TaskInfo) (*Batch, error) {
 
 	var dat []byte
+	var feeder *script.Feeder
 
-	info, err := database.GetBehavior().Find(name)
+	info, err := database.GetBehavior().Find(task.Name)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("can't find behavior %v", task.Name)
 	}
 
-	dat = info.File
-	cfg, err := database.GetConfig().Get()
+	if task.Seed == 0 {
+		task.Seed = time.Now().UnixNano()
+	}
+
+	if task.Dataset != "" {
+		ds, err := database.GetDataset().Find(task.Dataset)
+		if err != nil {
+			return nil, fmt.Errorf("can't find dataset %v", task.Dataset)
+		}
+
+		feeder, err = script.NewFeeder(task.Dataset, ds.Data, task.Feed, task.Seed)
+		if err != nil {
+			return nil, err
+		}
+		feeder.SetOffset(int(task.Offset))
+	}
+
+	// 子树只在创建 batch 时获取一次
+	dat, err = behavior.Expand(info.File)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return CreateBatch(name, cur, num, dat, BatchConfig{
+	cfg, err := database.GetConfig().Get()
+	if err != nil {
+		return nil, err
+	}
+	return CreateBatch(task.Name, task.Cur, task.Num, dat, BatchConfig{
 		batchsize:     int32(cfg.ChannelSize),
 		globalScript:  string(cfg.GlobalCode),
 		scriptPath:    f.parm.ScriptPath,
 		enqeueneDelay: int32(cfg.EnqueneDelay),
-	})
+		feeder:        feeder,
+		seed:          task.Seed,
+		thinktime:     cfg.ThinkTime,
+		workers:       f.parm.Workers,
+		httpOptions:   httpOptions(cfg),
+	}), nil
 }
 
 func (f *Factory) CreateDebugBot(name string, fbyt []byte) *bot.Bot {
@@ -129,8 +216,9 @@ func (f *Factory) CreateDebugBot(name string, fbyt []byte) *bot.Bot {
 	if err != nil {
 		return nil
 	}
-
 	b = bot.NewWithBehaviorTree(f.parm.ScriptPath, tree, name, "", 1, string(cfg.GlobalCode))
+	b.SetThinkTime(cfg.ThinkTime)
+	b.SetHttpOptions(httpOptions(cfg))
 	f.debugBots[b.ID()] = b
 
 	return b
@@ -146,6 +234,9 @@ func (f *Factory) FindBot(botid string) *bot.Bot {
 }
 
 func (f *Factory) RmvBot(botid string) {
+	if b, ok := f.debugBots[botid]; ok {
+		b.Close()
+	}
 	delete(f.debugBots, botid)
 }
 
@@ -156,11 +247,15 @@ func (f *Factory) taskLoop() {
 			info := f.pipelineCache[0]
 			f.pipelineCache = f.pipelineCache[1:]
 
-			b := f.createBatch(info.Name, info.Cur, info.Num)
-
-			f.pushBatch(b)
-			<-b.BatchDone
-			f.popBatch()
+			b, err := f.createBatch(info)
+			if err != nil {
+				fmt.Println("create batch", info.Name, "err", err.Error())
+				database.GetBehavior().UpdateStatus(info.Name, bot.BotStatusFail)
+			} else {
+				f.pushBatch(b)
+				<-b.BatchDone
+				f.popBatch()
+			}
 		}
 		f.lock.Unlock()
 
@@ -205,3 +300,14 @@ func (f *Factory) GetBatchInfo() []BatchInfo {
 	f.batchLock.Unlock()
 	return lst
 }
+
+// httpOptions ConfTable 中的 http 配置（行为树中没有单独设置时使用
+func httpOptions(cfg database.ConfTable) script.HttpOptions {
+	opts, err := script.ParseHttpOptions(cfg.HttpOptions)
+	if err != nil {
+		fmt.Println("parse http options err", err.Error())
+		return script.HttpOptions{}
+	}
+
+	return opts
+}
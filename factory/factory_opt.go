@@ -28,6 +28,11 @@ type Parm struct {
 
 	// 无数据库模式运行
 	NoDBMode bool
+
+	// 每个 batch 执行 bot 的 worker 数量
+	//
+	// 默认值 cpu 核数 * 8
+	Workers int
 }
 
 // Option consul discover config wrapper
@@ -50,3 +55,9 @@ func WithNoDatabase(flag bool) Option {
 		c.NoDBMode = flag
 	}
 }
+
+func WithWorkers(n int) Option {
+	return func(c *Parm) {
+		c.Workers = n
+	}
+}
@@ -23,6 +23,7 @@ var (
 	scriptPath   string
 	openHttpMock bool
 	openTcpMock  bool
+	workers      int
 )
 
 const (
@@ -50,6 +51,7 @@ func initFlag() {
 	flag.BoolVar(&openHttpMock, "httpmock", false, "open http mock server")
 	flag.BoolVar(&openTcpMock, "tcpmock", false, "open tcp mock server")
 	flag.StringVar(&scriptPath, "script_path", "script/", "Path to bot script")
+	flag.IntVar(&workers, "workers", 0, "Number of workers running the bots of a batch (default cpu * 8)")
 }
 
 func main() {
@@ -71,6 +73,7 @@ func main() {
 
 	_, err := factory.Create(
 		factory.WithNoDatabase(dbmode),
+		factory.WithWorkers(workers),
 	)
 	if err != nil {
 		panic(err)
@@ -22,6 +22,11 @@
 -- SOFTWARE.
 --
 
+-- bot 中已经预置了 go 实现的 json 模块（带有 json.null），这里不再覆盖
+if type(json) == "table" and json.null ~= nil then
+  return
+end
+
 json = { _version = "0.1.2" }
 
 -------------------------------------------------------------------------------
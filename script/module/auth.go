@@ -0,0 +1,386 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pojol/gobot/utils"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// 默认在 token 过期前 30 秒刷新
+const defaultRefreshBefore = 30
+
+type authConfig struct {
+	URL           string
+	Grant         string // client_credentials / password
+	ClientID      string
+	ClientSecret  string
+	Username      string
+	Password      string
+	Scope         string
+	RefreshBefore time.Duration
+	Inject        bool
+	Hosts         []string // 自动注入 token 的 host，为空时只注入到 token 接口和 session base_url 的 host，"*" 为所有的 host
+}
+
+type authToken struct {
+	conf         authConfig
+	accessToken  string
+	refreshToken string
+	tokenType    string
+	expire       time.Time // 为空表示不会过期
+}
+
+func (t *authToken) valid() bool {
+	if t.accessToken == "" {
+		return false
+	}
+	if t.expire.IsZero() {
+		return true
+	}
+	return time.Now().Add(t.conf.RefreshBefore).Before(t.expire)
+}
+
+// match 请求的 host 是否需要注入 token（base 为 session 的 base_url
+func (t *authToken) match(host string, base string) bool {
+	if !t.conf.Inject {
+		return false
+	}
+
+	hosts := t.conf.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{urlHost(t.conf.URL), urlHost(base)}
+	}
+	for _, h := range hosts {
+		if h == "*" || (h != "" && strings.EqualFold(h, host)) {
+			return true
+		}
+	}
+	return false
+}
+
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// AuthModule oauth2 token 的获取和缓存（每个 bot 独立），以及 jwt 的签发和校验
+type AuthModule struct {
+	http   *HttpModule
+	tokens map[string]*authToken
+	order  []string
+}
+
+// NewAuthModule 获取到的 token 会自动注入到 httpMod 发出的请求中
+func NewAuthModule(httpMod *HttpModule) *AuthModule {
+	a := &AuthModule{
+		http:   httpMod,
+		tokens: make(map[string]*authToken),
+	}
+	httpMod.authorize = a.authorize
+
+	return a
+}
+
+func (a *AuthModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"token": a.token,
+		"clear": a.clear,
+
+		"jwt_sign":   a.jwtSign,
+		"jwt_verify": a.jwtVerify,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// Reset 清理缓存的 token（lua state 放回池中时调用
+func (a *AuthModule) Reset() {
+	a.tokens = make(map[string]*authToken)
+	a.order = a.order[:0]
+}
+
+func parseAuthConfig(opts *lua.LTable) (authConfig, error) {
+	conf := authConfig{
+		URL:           lua.LVAsString(opts.RawGetString("url")),
+		Grant:         lua.LVAsString(opts.RawGetString("grant")),
+		ClientID:      lua.LVAsString(opts.RawGetString("client_id")),
+		ClientSecret:  lua.LVAsString(opts.RawGetString("client_secret")),
+		Username:      lua.LVAsString(opts.RawGetString("username")),
+		Password:      lua.LVAsString(opts.RawGetString("password")),
+		Scope:         lua.LVAsString(opts.RawGetString("scope")),
+		RefreshBefore: time.Second * defaultRefreshBefore,
+		Inject:        true,
+	}
+
+	if conf.URL == "" {
+		return conf, errors.New("token url is empty")
+	}
+	if conf.Grant == "" {
+		conf.Grant = "client_credentials"
+	}
+	if conf.Grant != "client_credentials" && conf.Grant != "password" {
+		return conf, fmt.Errorf("unsupported grant %v", conf.Grant)
+	}
+
+	if n, ok := opts.RawGetString("refresh_before").(lua.LNumber); ok {
+		conf.RefreshBefore = time.Second * time.Duration(n)
+	}
+	if v, ok := opts.RawGetString("inject").(lua.LBool); ok {
+		conf.Inject = bool(v)
+	}
+	if hosts, ok := opts.RawGetString("hosts").(*lua.LTable); ok {
+		for i := 1; i <= hosts.Len(); i++ {
+			conf.Hosts = append(conf.Hosts, hosts.RawGetInt(i).String())
+		}
+	}
+
+	return conf, nil
+}
+
+// token(name, opts) 获取 token，已经缓存并且没有临近过期时直接返回缓存的 token
+//
+//	local token, err = auth.token("api", {
+//	    url = "http://127.0.0.1:8888/oauth/token",
+//	    grant = "client_credentials",   -- client_credentials / password
+//	    client_id = "", client_secret = "",
+//	    username = "", password = "",   -- password grant
+//	    scope = "",
+//	    refresh_before = 30,            -- 过期前多少秒刷新
+//	    inject = true,                  -- 自动注入到 http 模块的请求中（Authorization: Bearer
+//	    hosts = { "127.0.0.1:8888" },   -- 只注入到这些 host，为空时为 token 接口和 session base_url 的 host，{ "*" } 注入到所有请求
+//	})
+//
+// 之后可以通过 auth.token(name) 获取当前的 token（会在需要时刷新
+func (a *AuthModule) token(L *lua.LState) int {
+	name := L.CheckString(1)
+
+	t, ok := a.tokens[name]
+	if opts := L.ToTable(2); opts != nil {
+		conf, err := parseAuthConfig(opts)
+		if err != nil {
+			return pushErr(L, err)
+		}
+		if !ok || t.conf.URL != conf.URL || t.conf.Grant != conf.Grant || t.conf.ClientID != conf.ClientID || t.conf.Username != conf.Username {
+			t = &authToken{}
+			if !ok {
+				a.order = append(a.order, name)
+			}
+			a.tokens[name] = t
+		}
+		t.conf = conf
+	} else if !ok {
+		return pushErr(L, fmt.Errorf("token %v not configured", name))
+	}
+
+	err := a.ensure(L, t)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, lua.LString(t.accessToken))
+}
+
+func (a *AuthModule) clear(L *lua.LState) int {
+	if L.GetTop() == 0 {
+		a.Reset()
+	} else {
+		name := L.CheckString(1)
+		delete(a.tokens, name)
+		for k, v := range a.order {
+			if v == name {
+				a.order = append(a.order[:k], a.order[k+1:]...)
+				break
+			}
+		}
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// ensure 在 token 无效或者临近过期时重新获取（有 refresh_token 时优先刷新
+func (a *AuthModule) ensure(L *lua.LState, t *authToken) error {
+	if t.valid() {
+		return nil
+	}
+
+	if t.refreshToken != "" {
+		err := a.fetch(L, t, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {t.refreshToken},
+		})
+		if err == nil {
+			return nil
+		}
+		t.refreshToken = ""
+	}
+
+	form := url.Values{
+		"grant_type": {t.conf.Grant},
+	}
+	if t.conf.Grant == "password" {
+		form.Set("username", t.conf.Username)
+		form.Set("password", t.conf.Password)
+	}
+	if t.conf.Scope != "" {
+		form.Set("scope", t.conf.Scope)
+	}
+
+	return a.fetch(L, t, form)
+}
+
+func (a *AuthModule) fetch(L *lua.LState, t *authToken, form url.Values) error {
+	form.Set("client_id", t.conf.ClientID)
+	if t.conf.ClientSecret != "" {
+		form.Set("client_secret", t.conf.ClientSecret)
+	}
+
+	body := []byte(form.Encode())
+	req, err := http.NewRequest("POST", t.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if ctx := L.Context(); ctx != nil {
+		req = req.WithContext(ctx)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	// token 请求同样计入报告
+	res, resbody, inf, err := a.http.do(a.http.client, req, t.conf.URL, len(body))
+	if err == nil && res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("token status %v : %s", res.StatusCode, resbody)
+		inf.Err = err.Error()
+	}
+	a.http.repolst = append(a.http.repolst, inf)
+	if err != nil {
+		return err
+	}
+
+	ret := struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}{}
+	err = json.Unmarshal(resbody, &ret)
+	if err != nil {
+		return fmt.Errorf("token response err %w", err)
+	}
+	if ret.AccessToken == "" {
+		return errors.New("token response without access_token")
+	}
+
+	t.accessToken = ret.AccessToken
+	t.tokenType = ret.TokenType
+	if ret.RefreshToken != "" {
+		t.refreshToken = ret.RefreshToken
+	}
+	t.expire = time.Time{}
+	if ret.ExpiresIn > 0 {
+		t.expire = time.Now().Add(time.Second * time.Duration(ret.ExpiresIn))
+	}
+
+	return nil
+}
+
+// authorize 为 http 模块的请求注入 token（请求中已经设置了 Authorization 时不处理
+func (a *AuthModule) authorize(L *lua.LState, req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+
+	for _, name := range a.order {
+		t := a.tokens[name]
+		if !t.match(req.URL.Host, a.http.session.baseURL) {
+			continue
+		}
+
+		err := a.ensure(L, t)
+		if err != nil {
+			return fmt.Errorf("auth token %v err %w", name, err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+t.accessToken)
+		return nil
+	}
+
+	return nil
+}
+
+func jwtKey(alg string, key string, sign bool) (interface{}, error) {
+	switch alg {
+	case "HS256":
+		return []byte(key), nil
+	case "RS256":
+		pem, err := loadPEM(key)
+		if err != nil {
+			return nil, err
+		}
+		if sign {
+			return jwt.ParseRSAPrivateKeyFromPEM(pem)
+		}
+		return jwt.ParseRSAPublicKeyFromPEM(pem)
+	}
+
+	return nil, fmt.Errorf("unsupported jwt alg %v", alg)
+}
+
+// jwt_sign(claims, key, alg) alg 支持 HS256（key 为密钥）/ RS256（key 为私钥 pem 或文件名
+func (a *AuthModule) jwtSign(L *lua.LState) int {
+	claims := L.CheckTable(1)
+	alg := L.OptString(3, "HS256")
+
+	key, err := jwtKey(alg, L.CheckString(2), true)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	m, err := utils.Table2MgoMap(claims)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.GetSigningMethod(alg), jwt.MapClaims(m)).SignedString(key)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, lua.LString(token))
+}
+
+// jwt_verify(token, key, alg) 校验签名和过期时间，返回 claims
+func (a *AuthModule) jwtVerify(L *lua.LState) int {
+	tokenstr := L.CheckString(1)
+	alg := L.OptString(3, "HS256")
+
+	key, err := jwtKey(alg, L.CheckString(2), false)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenstr, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != alg {
+			return nil, fmt.Errorf("unexpected jwt alg %v", t.Method.Alg())
+		}
+		return key, nil
+	})
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, utils.ToLuaValue(L, map[string]interface{}(claims)))
+}
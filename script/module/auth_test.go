@@ -0,0 +1,168 @@
+package script
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestAuthToken(t *testing.T) {
+	issued, refreshed := 0, 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/token":
+			req.ParseForm()
+			if req.PostForm.Get("client_id") != "bot" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch req.PostForm.Get("grant_type") {
+			case "client_credentials":
+				issued++
+			case "refresh_token":
+				refreshed++
+			}
+			fmt.Fprintf(w, `{"access_token":"t%d","refresh_token":"r","token_type":"bearer","expires_in":%s}`,
+				issued+refreshed, req.URL.Query().Get("exp"))
+		case "/api":
+			w.Write([]byte(req.Header.Get("Authorization")))
+		}
+	}))
+	defer ts.Close()
+
+	httpMod := NewHttpModule()
+	authMod := NewAuthModule(httpMod)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+	L.PreloadModule("auth", authMod.Loader)
+	L.SetGlobal("url", lua.LString(ts.URL))
+
+	err := L.DoString(`
+		local http = require("http")
+		local auth = require("auth")
+
+		local token, err = auth.token("api", {url = url .. "/token?exp=3600", client_id = "bot", scope = "read"})
+		assert(err == "succ", err)
+		assert(token == "t1", token)
+
+		local res = http.get(url .. "/api")
+		assert(res["body"] == "Bearer t1", res["body"])
+
+		res = http.get(url .. "/api", {headers = {Authorization = "Basic x"}})
+		assert(res["body"] == "Basic x", res["body"])
+
+		-- 临近过期时使用 refresh_token 刷新
+		token, err = auth.token("short", {url = url .. "/token?exp=10", client_id = "bot", refresh_before = 30, hosts = {"none"}})
+		assert(token == "t2", token)
+		token, err = auth.token("short")
+		assert(token == "t3", token)
+
+		token, err = auth.token("bad", {url = url .. "/token?exp=10", client_id = "x"})
+		assert(token == nil and err ~= "succ", "bad client")
+
+		assert(auth.clear() == "succ")
+		res = http.get(url .. "/api")
+		assert(res["body"] == "", res["body"])
+	`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, issued, 2)
+	assert.Equal(t, refreshed, 1)
+}
+
+func TestAuthHosts(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			w.Write([]byte(`{"access_token":"t1","token_type":"bearer"}`))
+			return
+		}
+		w.Write([]byte(req.Header.Get("Authorization")))
+	})
+	ts := httptest.NewServer(echo)
+	defer ts.Close()
+	other := httptest.NewServer(echo)
+	defer other.Close()
+
+	httpMod := NewHttpModule()
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+	L.PreloadModule("auth", NewAuthModule(httpMod).Loader)
+	L.SetGlobal("url", lua.LString(ts.URL))
+	L.SetGlobal("other", lua.LString(other.URL))
+
+	err := L.DoString(`
+		local http = require("http")
+		local auth = require("auth")
+
+		-- 默认只注入到 token 接口的 host
+		auth.token("api", {url = url .. "/token", client_id = "bot"})
+		assert(http.get(url .. "/api")["body"] == "Bearer t1", "token host")
+		assert(http.get(other .. "/api")["body"] == "", "third party host")
+
+		-- 以及 session 的 base_url
+		http.session({base_url = other})
+		assert(http.get("/api")["body"] == "Bearer t1", "base url host")
+		http.session({base_url = ""})
+
+		auth.token("api", {url = url .. "/token", client_id = "bot", hosts = {"*"}})
+		assert(http.get(other .. "/api")["body"] == "Bearer t1", "all hosts")
+	`)
+	assert.Equal(t, err, nil)
+}
+
+func TestAuthJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, err, nil)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Equal(t, err, nil)
+
+	authMod := NewAuthModule(NewHttpModule())
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("auth", authMod.Loader)
+	L.SetGlobal("private", lua.LString(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})))
+	L.SetGlobal("public", lua.LString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})))
+
+	err = L.DoString(`
+		local auth = require("auth")
+
+		local token, err = auth.jwt_sign({sub = "bot001", exp = os.time() + 60}, "secret")
+		assert(err == "succ", err)
+		local claims, err = auth.jwt_verify(token, "secret")
+		assert(err == "succ", err)
+		assert(claims.sub == "bot001", "hs256 claims")
+
+		claims, err = auth.jwt_verify(token, "wrong")
+		assert(claims == nil, "wrong secret")
+
+		token, err = auth.jwt_sign({sub = "bot002"}, private, "RS256")
+		assert(err == "succ", err)
+		claims, err = auth.jwt_verify(token, public, "RS256")
+		assert(err == "succ", err)
+		assert(claims.sub == "bot002", "rs256 claims")
+
+		claims, err = auth.jwt_verify(token, "secret")
+		assert(claims == nil, "alg mismatch")
+
+		token, err = auth.jwt_sign({sub = "bot003", exp = os.time() - 60}, "secret")
+		claims, err = auth.jwt_verify(token, "secret")
+		assert(claims == nil, "expired")
+	`)
+	assert.Equal(t, err, nil)
+}
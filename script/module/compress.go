@@ -0,0 +1,129 @@
+package script
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type CompressModule struct {
+}
+
+func (c *CompressModule) Loader(l *lua.LState) int {
+	mod := l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"gzip":     c.Gzip,
+		"gunzip":   c.Gunzip,
+		"zlib":     c.Zlib,
+		"unzlib":   c.Unzlib,
+		"snappy":   c.Snappy,
+		"unsnappy": c.Unsnappy,
+	})
+	l.Push(mod)
+	return 1
+}
+
+func (c *CompressModule) doCompress(alg string, dat []byte, level int) ([]byte, error) {
+	if alg == "snappy" {
+		return snappy.Encode(nil, dat), nil
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+
+	switch alg {
+	case "gzip":
+		w, err = gzip.NewWriterLevel(&buf, level)
+	case "zlib":
+		w, err = zlib.NewWriterLevel(&buf, level)
+	default:
+		return nil, fmt.Errorf("unsupported compress %v", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *CompressModule) doDecompress(alg string, dat []byte) ([]byte, error) {
+	if alg == "snappy" {
+		return snappy.Decode(nil, dat)
+	}
+
+	var r io.ReadCloser
+	var err error
+
+	switch alg {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(dat))
+	case "zlib":
+		r, err = zlib.NewReader(bytes.NewReader(dat))
+	default:
+		return nil, fmt.Errorf("unsupported compress %v", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func (c *CompressModule) push(l *lua.LState, v []byte, err error) int {
+	if err != nil {
+		l.Push(lua.LString(""))
+		l.Push(lua.LString(err.Error()))
+	} else {
+		l.Push(lua.LString(v))
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
+// gzip(data, level) level 默认为 -1（gzip.DefaultCompression
+func (c *CompressModule) Gzip(l *lua.LState) int {
+	v, err := c.doCompress("gzip", []byte(l.ToString(1)), l.OptInt(2, gzip.DefaultCompression))
+	return c.push(l, v, err)
+}
+
+func (c *CompressModule) Gunzip(l *lua.LState) int {
+	v, err := c.doDecompress("gzip", []byte(l.ToString(1)))
+	return c.push(l, v, err)
+}
+
+func (c *CompressModule) Zlib(l *lua.LState) int {
+	v, err := c.doCompress("zlib", []byte(l.ToString(1)), l.OptInt(2, zlib.DefaultCompression))
+	return c.push(l, v, err)
+}
+
+func (c *CompressModule) Unzlib(l *lua.LState) int {
+	v, err := c.doDecompress("zlib", []byte(l.ToString(1)))
+	return c.push(l, v, err)
+}
+
+func (c *CompressModule) Snappy(l *lua.LState) int {
+	v, err := c.doCompress("snappy", []byte(l.ToString(1)), 0)
+	return c.push(l, v, err)
+}
+
+func (c *CompressModule) Unsnappy(l *lua.LState) int {
+	v, err := c.doDecompress("snappy", []byte(l.ToString(1)))
+	return c.push(l, v, err)
+}
@@ -0,0 +1,40 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestCompress(t *testing.T) {
+	compressMod := CompressModule{}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("compress", compressMod.Loader)
+
+	err := L.DoString(`
+		local compress = require("compress")
+		local dat = string.rep("gobot", 100)
+
+		for _, alg in ipairs({"gzip", "zlib", "snappy"}) do
+			local enc, err = compress[alg](dat)
+			assert(err == nil, err)
+			assert(#enc < #dat, alg .. " size")
+
+			local un = ({gzip = "gunzip", zlib = "unzlib", snappy = "unsnappy"})[alg]
+			local dec, err = compress[un](enc)
+			assert(err == nil, err)
+			assert(dec == dat, alg .. " roundtrip")
+
+			dec, err = compress[un]("bad data")
+			assert(err ~= nil, alg .. " bad data")
+		end
+
+		local best = compress.gzip(dat, 9)
+		assert(compress.gunzip(best) == dat, "gzip level")
+	`)
+	assert.Equal(t, err, nil)
+}
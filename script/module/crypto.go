@@ -0,0 +1,342 @@
+package script
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// CryptoModule 摘要、hmac、aes、rsa 相关的接口（输入输出都是原始的二进制字符串，需要时通过 hex / base64 转换
+type CryptoModule struct {
+}
+
+func (c *CryptoModule) Loader(l *lua.LState) int {
+	mod := l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"sha1":   c.Sha1,
+		"sha256": c.Sha256,
+		"sha512": c.Sha512,
+		"hmac":   c.Hmac,
+
+		"aes_cbc_encrypt": c.AesCbcEncrypt,
+		"aes_cbc_decrypt": c.AesCbcDecrypt,
+		"aes_gcm_encrypt": c.AesGcmEncrypt,
+		"aes_gcm_decrypt": c.AesGcmDecrypt,
+
+		"rsa_sign":   c.RsaSign,
+		"rsa_verify": c.RsaVerify,
+
+		"random_bytes": c.RandomBytes,
+		"hex":          c.Hex,
+		"unhex":        c.Unhex,
+	})
+	l.Push(mod)
+	return 1
+}
+
+func pushCryptoRet(l *lua.LState, v []byte, err error) int {
+	if err != nil {
+		l.Push(lua.LString(""))
+		l.Push(lua.LString(err.Error()))
+	} else {
+		l.Push(lua.LString(v))
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
+func hashFunc(alg string) (func() hash.Hash, crypto.Hash, error) {
+	switch alg {
+	case "md5":
+		return md5.New, crypto.MD5, nil
+	case "sha1":
+		return sha1.New, crypto.SHA1, nil
+	case "sha256", "":
+		return sha256.New, crypto.SHA256, nil
+	case "sha512":
+		return sha512.New, crypto.SHA512, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported hash %v", alg)
+}
+
+func (c *CryptoModule) doSum(alg string, dat []byte) ([]byte, error) {
+	fn, _, err := hashFunc(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	h := fn()
+	h.Write(dat)
+	return h.Sum(nil), nil
+}
+
+func (c *CryptoModule) Sha1(l *lua.LState) int {
+	v, err := c.doSum("sha1", []byte(l.ToString(1)))
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) Sha256(l *lua.LState) int {
+	v, err := c.doSum("sha256", []byte(l.ToString(1)))
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) Sha512(l *lua.LState) int {
+	v, err := c.doSum("sha512", []byte(l.ToString(1)))
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) doHmac(alg string, key []byte, dat []byte) ([]byte, error) {
+	fn, _, err := hashFunc(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hmac.New(fn, key)
+	h.Write(dat)
+	return h.Sum(nil), nil
+}
+
+// hmac(alg, key, data) alg 支持 md5 / sha1 / sha256 / sha512
+func (c *CryptoModule) Hmac(l *lua.LState) int {
+	v, err := c.doHmac(l.ToString(1), []byte(l.ToString(2)), []byte(l.ToString(3)))
+	return pushCryptoRet(l, v, err)
+}
+
+func pkcs7Pad(dat []byte, size int) []byte {
+	n := size - len(dat)%size
+	return append(dat, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(dat []byte, size int) ([]byte, error) {
+	if len(dat) == 0 || len(dat)%size != 0 {
+		return nil, errors.New("invalid padding size")
+	}
+
+	n := int(dat[len(dat)-1])
+	if n == 0 || n > size || n > len(dat) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, v := range dat[len(dat)-n:] {
+		if int(v) != n {
+			return nil, errors.New("invalid padding")
+		}
+	}
+
+	return dat[:len(dat)-n], nil
+}
+
+func (c *CryptoModule) doAesCbc(key, iv, dat []byte, encrypt bool) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("iv length must be %v", block.BlockSize())
+	}
+
+	if encrypt {
+		dat = pkcs7Pad(append([]byte{}, dat...), block.BlockSize())
+		out := make([]byte, len(dat))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, dat)
+		return out, nil
+	}
+
+	if len(dat) == 0 || len(dat)%block.BlockSize() != 0 {
+		return nil, errors.New("ciphertext is not a multiple of the block size")
+	}
+	out := make([]byte, len(dat))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, dat)
+	return pkcs7Unpad(out, block.BlockSize())
+}
+
+// aes_cbc_encrypt(key, iv, plaintext) 使用 pkcs7 填充，key 长度 16 / 24 / 32
+func (c *CryptoModule) AesCbcEncrypt(l *lua.LState) int {
+	v, err := c.doAesCbc([]byte(l.ToString(1)), []byte(l.ToString(2)), []byte(l.ToString(3)), true)
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) AesCbcDecrypt(l *lua.LState) int {
+	v, err := c.doAesCbc([]byte(l.ToString(1)), []byte(l.ToString(2)), []byte(l.ToString(3)), false)
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) doAesGcm(key, nonce, dat, aad []byte, encrypt bool) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("nonce length must be %v", gcm.NonceSize())
+	}
+
+	if encrypt {
+		return gcm.Seal(nil, nonce, dat, aad), nil
+	}
+	return gcm.Open(nil, nonce, dat, aad)
+}
+
+// aes_gcm_encrypt(key, nonce, plaintext, aad) 返回的密文末尾带有 16 字节的 tag，nonce 长度 12
+func (c *CryptoModule) AesGcmEncrypt(l *lua.LState) int {
+	v, err := c.doAesGcm([]byte(l.ToString(1)), []byte(l.ToString(2)), []byte(l.ToString(3)), []byte(l.OptString(4, "")), true)
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) AesGcmDecrypt(l *lua.LState) int {
+	v, err := c.doAesGcm([]byte(l.ToString(1)), []byte(l.ToString(2)), []byte(l.ToString(3)), []byte(l.OptString(4, "")), false)
+	return pushCryptoRet(l, v, err)
+}
+
+func parsePrivateKey(v string) (*rsa.PrivateKey, error) {
+	byt, err := loadPEM(v)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(byt)
+	if block == nil {
+		return nil, errors.New("invalid private key pem")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsakey, ok := key.(*rsa.PrivateKey); ok {
+		return rsakey, nil
+	}
+	return nil, errors.New("not a rsa private key")
+}
+
+func parsePublicKey(v string) (*rsa.PublicKey, error) {
+	byt, err := loadPEM(v)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(byt)
+	if block == nil {
+		return nil, errors.New("invalid public key pem")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rsakey, ok := key.(*rsa.PublicKey); ok {
+		return rsakey, nil
+	}
+	return nil, errors.New("not a rsa public key")
+}
+
+func (c *CryptoModule) doRsaSign(keypem string, dat []byte, alg string) ([]byte, error) {
+	key, err := parsePrivateKey(keypem)
+	if err != nil {
+		return nil, err
+	}
+
+	fn, h, err := hashFunc(alg)
+	if err != nil {
+		return nil, err
+	}
+	hs := fn()
+	hs.Write(dat)
+
+	return rsa.SignPKCS1v15(rand.Reader, key, h, hs.Sum(nil))
+}
+
+// rsa_sign(private_key, data, alg) 使用 PKCS#1 v1.5 签名，private_key 为 pem 文本或者脚本目录下的文件名，alg 默认 sha256
+func (c *CryptoModule) RsaSign(l *lua.LState) int {
+	v, err := c.doRsaSign(l.ToString(1), []byte(l.ToString(2)), l.OptString(3, "sha256"))
+	return pushCryptoRet(l, v, err)
+}
+
+func (c *CryptoModule) doRsaVerify(keypem string, dat []byte, sig []byte, alg string) error {
+	key, err := parsePublicKey(keypem)
+	if err != nil {
+		return err
+	}
+
+	fn, h, err := hashFunc(alg)
+	if err != nil {
+		return err
+	}
+	hs := fn()
+	hs.Write(dat)
+
+	return rsa.VerifyPKCS1v15(key, h, hs.Sum(nil), sig)
+}
+
+// rsa_verify(public_key, data, sig, alg) 返回 true / false, err
+func (c *CryptoModule) RsaVerify(l *lua.LState) int {
+	err := c.doRsaVerify(l.ToString(1), []byte(l.ToString(2)), []byte(l.ToString(3)), l.OptString(4, "sha256"))
+
+	l.Push(lua.LBool(err == nil))
+	if err != nil {
+		l.Push(lua.LString(err.Error()))
+	} else {
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
+// random_bytes(n) 安全的随机字节
+func (c *CryptoModule) RandomBytes(l *lua.LState) int {
+	n := l.CheckInt(1)
+	if n < 0 {
+		return pushCryptoRet(l, nil, errors.New("n must be positive"))
+	}
+
+	byt := make([]byte, n)
+	_, err := rand.Read(byt)
+	return pushCryptoRet(l, byt, err)
+}
+
+func (c *CryptoModule) Hex(l *lua.LState) int {
+	l.Push(lua.LString(hex.EncodeToString([]byte(l.ToString(1)))))
+	return 1
+}
+
+func (c *CryptoModule) Unhex(l *lua.LState) int {
+	v, err := hex.DecodeString(l.ToString(1))
+	return pushCryptoRet(l, v, err)
+}
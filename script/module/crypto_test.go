@@ -0,0 +1,66 @@
+package script
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestCrypto(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, err, nil)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Equal(t, err, nil)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Equal(t, err, nil)
+
+	cryptoMod := CryptoModule{}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("crypto", cryptoMod.Loader)
+	L.SetGlobal("private", lua.LString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})))
+	L.SetGlobal("public", lua.LString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})))
+
+	err = L.DoString(`
+		local crypto = require("crypto")
+
+		assert(crypto.hex(crypto.sha1("abc")) == "a9993e364706816aba3e25717850c26c9cd0d89d", "sha1")
+		assert(crypto.hex(crypto.sha256("abc")) == "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", "sha256")
+		assert(#crypto.sha512("abc") == 64, "sha512")
+
+		local mac = crypto.hmac("sha256", "key", "The quick brown fox jumps over the lazy dog")
+		assert(crypto.hex(mac) == "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", "hmac")
+
+		local aeskey = crypto.random_bytes(32)
+		local iv = crypto.random_bytes(16)
+		local enc, err = crypto.aes_cbc_encrypt(aeskey, iv, "hello gobot")
+		assert(err == nil, err)
+		assert(#enc == 16, "cbc padding")
+		local dec = crypto.aes_cbc_decrypt(aeskey, iv, enc)
+		assert(dec == "hello gobot", dec)
+
+		local nonce = crypto.random_bytes(12)
+		enc, err = crypto.aes_gcm_encrypt(aeskey, nonce, "payload", "aad")
+		assert(err == nil, err)
+		dec, err = crypto.aes_gcm_decrypt(aeskey, nonce, enc, "aad")
+		assert(dec == "payload", dec)
+		dec, err = crypto.aes_gcm_decrypt(aeskey, nonce, enc, "other")
+		assert(err ~= nil, "gcm aad")
+
+		local sig, err = crypto.rsa_sign(private, "data")
+		assert(err == nil, err)
+		assert(crypto.rsa_verify(public, "data", sig) == true, "rsa verify")
+		assert(crypto.rsa_verify(public, "other", sig) == false, "rsa verify other")
+
+		local _, err = crypto.unhex("zz")
+		assert(err ~= nil, "unhex")
+	`)
+	assert.Equal(t, err, nil)
+}
@@ -0,0 +1,155 @@
+package script
+
+import (
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// 模块触发的事件
+const (
+	EventTCP          = "tcp"     // tcp 连接有数据可以读取
+	EventSharedPrefix = "shared:" // 共享数据被修改（shared.watch(key) 后触发 shared:key
+)
+
+// Events bot 的事件，事件触发后由 WaitForEventNode 消费（在等待前触发的事件同样有效
+// 可以在其他的 goroutine 中触发
+type Events struct {
+	sync.Mutex
+
+	fired  map[string]bool
+	timers map[string]*time.Timer // 还没有触发的定时器（触发后移除
+	wake   chan struct{}
+	notify func() // 事件触发时调用（调度器唤醒 bot
+}
+
+func NewEvents() *Events {
+	return &Events{
+		fired:  make(map[string]bool),
+		timers: make(map[string]*time.Timer),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Signal 触发事件，并唤醒正在休眠的 bot
+func (e *Events) Signal(name string) {
+	e.Lock()
+	e.fired[name] = true
+	notify := e.notify
+	e.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// SetNotify 设置事件触发时的回调
+func (e *Events) SetNotify(f func()) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.notify = f
+}
+
+// After 在 d 之后触发事件（同名的定时器还没有触发时会被替换
+func (e *Events) After(name string, d time.Duration) {
+	e.Lock()
+	defer e.Unlock()
+
+	if old, ok := e.timers[name]; ok {
+		old.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		e.Lock()
+		// 已经被替换或者 Reset 的定时器（Stop 时已经触发
+		if e.timers[name] != timer {
+			e.Unlock()
+			return
+		}
+		delete(e.timers, name)
+		e.Unlock()
+
+		e.Signal(name)
+	})
+	e.timers[name] = timer
+}
+
+// Take 消费事件，返回事件是否已经触发
+func (e *Events) Take(name string) bool {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.fired[name] {
+		delete(e.fired, name)
+		return true
+	}
+
+	return false
+}
+
+// Wake 有事件触发时可读
+func (e *Events) Wake() <-chan struct{} {
+	return e.wake
+}
+
+// Reset 清理未消费的事件和定时器
+func (e *Events) Reset() {
+	e.Lock()
+	defer e.Unlock()
+
+	for _, t := range e.timers {
+		t.Stop()
+	}
+	e.timers = make(map[string]*time.Timer)
+	e.fired = make(map[string]bool)
+	e.notify = nil
+
+	select {
+	case <-e.wake:
+	default:
+	}
+}
+
+// EventModule 在脚本中触发事件
+//
+//	local event = require("event")
+//	event.signal("login")
+//	event.after("heartbeat", 5)
+type EventModule struct {
+	events *Events
+}
+
+func NewEventModule(e *Events) *EventModule {
+	return &EventModule{events: e}
+}
+
+func (m *EventModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"signal": m.signal,
+		"after":  m.after,
+	})
+	L.Push(mod)
+	return 1
+}
+
+func (m *EventModule) signal(L *lua.LState) int {
+	m.events.Signal(L.CheckString(1))
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// after(name, seconds) 定时触发事件（同名的定时器还没有触发时会被替换
+func (m *EventModule) after(L *lua.LState) int {
+	name := L.CheckString(1)
+	m.events.After(name, luaSeconds(L.CheckNumber(2), 0))
+	L.Push(lua.LString("succ"))
+	return 1
+}
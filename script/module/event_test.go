@@ -0,0 +1,159 @@
+package script
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func waitEvent(e *Events, name string, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		if e.Take(name) {
+			return true
+		}
+
+		select {
+		case <-e.Wake():
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func TestEventModule(t *testing.T) {
+	e := NewEvents()
+
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("event", NewEventModule(e).Loader)
+
+	err := L.DoString(`
+		local event = require("event")
+		assert(event.signal("login") == "succ")
+		assert(event.after("timer", 0.05) == "succ")
+		assert(event.after("reset", 0.05) == "succ")
+	`)
+	assert.Equal(t, err, nil)
+
+	assert.True(t, e.Take("login"))
+	assert.False(t, e.Take("login"))
+
+	begin := time.Now()
+	assert.True(t, waitEvent(e, "timer", time.Second))
+	assert.GreaterOrEqual(t, time.Since(begin), 40*time.Millisecond)
+	assert.True(t, e.Take("reset") || waitEvent(e, "reset", time.Second))
+
+	// 重置后定时器不再触发
+	e.After("timer", 20*time.Millisecond)
+	e.Signal("login")
+	e.Reset()
+	assert.False(t, e.Take("login"))
+	assert.False(t, waitEvent(e, "timer", 100*time.Millisecond))
+}
+
+func TestEventAfterTimers(t *testing.T) {
+	e := NewEvents()
+
+	// 触发后的定时器被移除
+	e.After("tick", time.Millisecond)
+	assert.True(t, waitEvent(e, "tick", time.Second))
+	e.Lock()
+	assert.Equal(t, len(e.timers), 0)
+	e.Unlock()
+
+	// 同名的定时器被替换，只触发一次
+	e.After("tick", 10*time.Millisecond)
+	e.After("tick", 30*time.Millisecond)
+	e.Lock()
+	assert.Equal(t, len(e.timers), 1)
+	e.Unlock()
+
+	begin := time.Now()
+	assert.True(t, waitEvent(e, "tick", time.Second))
+	assert.GreaterOrEqual(t, time.Since(begin), 20*time.Millisecond)
+	assert.False(t, waitEvent(e, "tick", 50*time.Millisecond))
+
+	// 定时器已经触发但还在等待锁的时候被 Reset，不会再触发事件
+	e.After("tick", time.Millisecond)
+	e.Lock()
+	time.Sleep(20 * time.Millisecond)
+	e.timers = make(map[string]*time.Timer)
+	e.Unlock()
+	assert.False(t, waitEvent(e, "tick", 50*time.Millisecond))
+}
+
+func TestSharedWatchEvent(t *testing.T) {
+	store := NewSharedStore()
+	e := NewEvents()
+
+	mod := NewSharedModule()
+	mod.SetEvents(e)
+	mod.SetStore(store)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("shared", mod.Loader)
+
+	err := L.DoString(`
+		local shared = require("shared")
+		assert(shared.watch("room") == "succ")
+		assert(shared.watch("tokens") == "succ")
+	`)
+	assert.Equal(t, err, nil)
+
+	store.Set("other", 1)
+	assert.False(t, e.Take(EventSharedPrefix+"other"))
+
+	store.Set("room", int64(1001))
+	assert.True(t, e.Take(EventSharedPrefix+"room"))
+
+	store.Incr("room", int64(1))
+	assert.True(t, e.Take(EventSharedPrefix+"room"))
+
+	store.Push("tokens", "a")
+	assert.True(t, e.Take(EventSharedPrefix+"tokens"))
+
+	// 切换 store 后不再监听之前的 store
+	mod.SetStore(nil)
+	store.Set("room", 1002)
+	assert.False(t, e.Take(EventSharedPrefix+"room"))
+}
+
+func TestTCPReadableEvent(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, err, nil)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	e := NewEvents()
+	mod := NewTCPModule()
+	mod.SetEvents(e)
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	assert.Equal(t, mod._dail("127.0.0.1", port, nil), nil)
+	defer mod.conn.Close()
+
+	server := <-accepted
+	defer server.Close()
+
+	assert.False(t, waitEvent(e, EventTCP, 50*time.Millisecond))
+
+	server.Write([]byte("hello"))
+	assert.True(t, waitEvent(e, EventTCP, time.Second))
+
+	buf := make([]byte, 16)
+	n, err := mod._recv(buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(buf[:n]), "hello")
+}
@@ -0,0 +1,218 @@
+package script
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// 数据集的分配策略
+const (
+	FeedSequential = "sequential" // 按顺序分配，分配完后不再返回数据
+	FeedCircular   = "circular"   // 按顺序循环分配
+	FeedRandom     = "random"     // 随机分配（可能重复
+	FeedUnique     = "unique"     // 随机分配且不重复，分配完后不再返回数据
+)
+
+var ErrFeederExhausted = errors.New("dataset exhausted")
+
+// Feeder 一个 batch 内所有 bot 共享的数据集（csv / json
+type Feeder struct {
+	Name     string
+	Strategy string
+
+	sync.Mutex
+	rows   []map[string]interface{}
+	cursor int
+	order  []int // unique 模式下打乱后的顺序
+	rand   *rand.Rand
+}
+
+// ParseDataset 解析数据集，json 格式为对象数组，csv 第一行为字段名
+func ParseDataset(dat []byte) ([]map[string]interface{}, error) {
+	trimed := bytes.TrimSpace(dat)
+	if len(trimed) == 0 {
+		return nil, errors.New("dataset is empty")
+	}
+
+	rows := []map[string]interface{}{}
+
+	if trimed[0] == '[' {
+		err := json.Unmarshal(trimed, &rows)
+		if err != nil {
+			return nil, fmt.Errorf("parse json dataset err %w", err)
+		}
+		return rows, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(trimed)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv dataset err %w", err)
+	}
+	if len(records) < 1 {
+		return nil, errors.New("csv dataset without header")
+	}
+
+	header := records[0]
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for k, field := range header {
+			row[field] = record[k]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func NewFeeder(name string, dat []byte, strategy string, seed int64) (*Feeder, error) {
+	if strategy == "" {
+		strategy = FeedSequential
+	}
+	switch strategy {
+	case FeedSequential, FeedCircular, FeedRandom, FeedUnique:
+	default:
+		return nil, fmt.Errorf("unknow feed strategy %v", strategy)
+	}
+
+	rows, err := ParseDataset(dat)
+	if err != nil {
+		return nil, err
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	f := &Feeder{
+		Name:     name,
+		Strategy: strategy,
+		rows:     rows,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+	if strategy == FeedUnique {
+		f.order = f.rand.Perm(len(rows))
+	}
+
+	return f, nil
+}
+
+func (f *Feeder) Size() int {
+	return len(f.rows)
+}
+
+// Offset 已经分配的行数（恢复任务时用于跳过已经分配的数据
+func (f *Feeder) Offset() int {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.cursor
+}
+
+// SetOffset 从第 n 行继续分配（unique 使用相同的种子时顺序相同
+func (f *Feeder) SetOffset(n int) {
+	f.Lock()
+	defer f.Unlock()
+
+	if n < 0 {
+		n = 0
+	}
+	f.cursor = n
+}
+
+// Next 按照分配策略获取下一行数据
+func (f *Feeder) Next() (map[string]interface{}, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if len(f.rows) == 0 {
+		return nil, ErrFeederExhausted
+	}
+
+	idx := 0
+	switch f.Strategy {
+	case FeedRandom:
+		idx = f.rand.Intn(len(f.rows))
+	case FeedCircular:
+		idx = f.cursor % len(f.rows)
+		f.cursor++
+	case FeedUnique:
+		if f.cursor >= len(f.order) {
+			return nil, ErrFeederExhausted
+		}
+		idx = f.order[f.cursor]
+		f.cursor++
+	default:
+		if f.cursor >= len(f.rows) {
+			return nil, ErrFeederExhausted
+		}
+		idx = f.cursor
+		f.cursor++
+	}
+
+	return f.rows[idx], nil
+}
+
+// FeederModule bot 获取 batch 数据集的接口
+//
+//	local row, err = feeder.next()
+type FeederModule struct {
+	feeder *Feeder
+}
+
+func NewFeederModule() *FeederModule {
+	return &FeederModule{}
+}
+
+func (m *FeederModule) SetFeeder(f *Feeder) {
+	m.feeder = f
+}
+
+func (m *FeederModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"next": m.next,
+		"size": m.size,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// Row 获取下一行数据并转换为 lua table
+func (m *FeederModule) Row(L *lua.LState) (lua.LValue, error) {
+	if m.feeder == nil {
+		return lua.LNil, errors.New("batch without dataset")
+	}
+
+	row, err := m.feeder.Next()
+	if err != nil {
+		return lua.LNil, err
+	}
+
+	return goToLua(L, row, codecOptions{}), nil
+}
+
+func (m *FeederModule) next(L *lua.LState) int {
+	row, err := m.Row(L)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, row)
+}
+
+func (m *FeederModule) size(L *lua.LState) int {
+	if m.feeder == nil {
+		L.Push(lua.LNumber(0))
+		return 1
+	}
+
+	L.Push(lua.LNumber(m.feeder.Size()))
+	return 1
+}
@@ -0,0 +1,111 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+var feederCSV = []byte(`name,pwd
+u1,p1
+u2,p2
+u3,p3
+`)
+
+func TestFeederStrategy(t *testing.T) {
+	f, err := NewFeeder("users", feederCSV, FeedSequential, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, f.Size(), 3)
+
+	for _, v := range []string{"u1", "u2", "u3"} {
+		row, err := f.Next()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, row["name"], v)
+	}
+	_, err = f.Next()
+	assert.Equal(t, err, ErrFeederExhausted)
+
+	f, _ = NewFeeder("users", feederCSV, FeedCircular, 1)
+	for _, v := range []string{"u1", "u2", "u3", "u1"} {
+		row, _ := f.Next()
+		assert.Equal(t, row["name"], v)
+	}
+
+	f, _ = NewFeeder("users", feederCSV, FeedUnique, 1)
+	seen := map[interface{}]bool{}
+	for i := 0; i < 3; i++ {
+		row, err := f.Next()
+		assert.Equal(t, err, nil)
+		seen[row["name"]] = true
+	}
+	assert.Equal(t, len(seen), 3)
+	_, err = f.Next()
+	assert.Equal(t, err, ErrFeederExhausted)
+
+	f, _ = NewFeeder("users", feederCSV, FeedRandom, 1)
+	for i := 0; i < 10; i++ {
+		_, err := f.Next()
+		assert.Equal(t, err, nil)
+	}
+
+	_, err = NewFeeder("users", feederCSV, "unknow", 1)
+	assert.NotEqual(t, err, nil)
+
+	_, err = NewFeeder("users", []byte("a,b\n1"), FeedSequential, 1)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestFeederOffset(t *testing.T) {
+	f, _ := NewFeeder("users", feederCSV, FeedUnique, 7)
+	first, _ := f.Next()
+	second, _ := f.Next()
+	assert.Equal(t, f.Offset(), 2)
+
+	// 恢复任务时使用相同的种子，从已经分配的行之后继续
+	f, _ = NewFeeder("users", feederCSV, FeedUnique, 7)
+	f.SetOffset(2)
+	row, err := f.Next()
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, row["name"], first["name"])
+	assert.NotEqual(t, row["name"], second["name"])
+	_, err = f.Next()
+	assert.Equal(t, err, ErrFeederExhausted)
+}
+
+func TestFeederModule(t *testing.T) {
+	f, err := NewFeeder("items", []byte(`[{"id":1,"tags":["a","b"]},{"id":2}]`), FeedSequential, 1)
+	assert.Equal(t, err, nil)
+
+	mod := NewFeederModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("feeder", mod.Loader)
+
+	err = L.DoString(`
+		local feeder = require("feeder")
+		local row, err = feeder.next()
+		assert(row == nil and err ~= "succ", "without dataset")
+		assert(feeder.size() == 0)
+	`)
+	assert.Equal(t, err, nil)
+
+	mod.SetFeeder(f)
+	err = L.DoString(`
+		local feeder = require("feeder")
+		assert(feeder.size() == 2)
+
+		local row, err = feeder.next()
+		assert(err == "succ", err)
+		assert(row.id == 1 and row.tags[2] == "b")
+
+		row, err = feeder.next()
+		assert(row.id == 2)
+
+		row, err = feeder.next()
+		assert(row == nil and err == "dataset exhausted", err)
+	`)
+	assert.Equal(t, err, nil)
+}
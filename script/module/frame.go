@@ -0,0 +1,97 @@
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+/*
+	消息帧格式（tcpconn / udpconn 共用
+
+	| 2 byte,   1 byte,     2 byte,     2byte		  |                        |
+	|包长度 len, 协议格式 ty, 预留2自定义字节, 协议号 msgid |                        |
+	|                  消息头                          |     消息体          |
+*/
+
+type byteQueue []byte
+
+// 入队
+func (q *byteQueue) push(b []byte) {
+	*q = append(*q, b...)
+}
+
+// 出队
+func (q *byteQueue) pop(maxLen int) ([]byte, bool) {
+	if len(*q) == 0 {
+		return nil, false
+	}
+
+	if maxLen > len(*q) {
+		maxLen = len(*q)
+	}
+
+	data := (*q)[:maxLen]
+	*q = (*q)[maxLen:]
+	return data, true
+}
+
+// 队列当前长度
+func (q *byteQueue) Len() int {
+	return len(*q)
+}
+
+// decodeFrame 从队列中解出一条消息，参数为消息头各字段的字节数
+func (q *byteQueue) decodeFrame(msglen, msgty, msgcustom, msgid int) (int, int, int, []byte, bool) {
+
+	msgleni := int16(0)
+	msgtyi := int8(0)
+	msgcustomi := int16(0)
+	msgidi := int16(0)
+
+	if q.Len() < msglen+msgty+msgcustom+msgid {
+		return 0, 0, 0, nil, false
+	}
+
+	msglenb, _ := q.pop(msglen)
+	binary.Read(bytes.NewBuffer(msglenb), binary.LittleEndian, &msgleni)
+
+	msgtyb, _ := q.pop(msgty)
+	binary.Read(bytes.NewBuffer(msgtyb), binary.LittleEndian, &msgtyi)
+
+	msgcustomb, _ := q.pop(msgcustom)
+	binary.Read(bytes.NewBuffer(msgcustomb), binary.LittleEndian, &msgcustomi)
+
+	msgidb, _ := q.pop(msgid)
+	binary.Read(bytes.NewBuffer(msgidb), binary.LittleEndian, &msgidi)
+
+	msgbody, _ := q.pop(int(msgleni) - (msgty + msgcustom + msgid))
+
+	return int(msgtyi), int(msgcustomi), int(msgidi), msgbody, true
+}
+
+// encodeFrame 按消息帧格式打包
+func encodeFrame(msglen, msgty, msgcustom, msgid int, msgbody string) []byte {
+
+	buf := bytes.NewBuffer(make([]byte, 0, msglen))
+
+	binary.Write(buf, binary.LittleEndian, uint16(msglen))
+	binary.Write(buf, binary.LittleEndian, uint8(msgty))
+	binary.Write(buf, binary.LittleEndian, uint16(msgcustom))
+	binary.Write(buf, binary.LittleEndian, uint16(msgid))
+	buf.WriteString(msgbody)
+
+	return buf.Bytes()
+}
+
+func readret(L *lua.LState, ty, custom, id int, body []byte, err string) int {
+
+	L.Push(lua.LNumber(ty))
+	L.Push(lua.LNumber(custom))
+	L.Push(lua.LNumber(id))
+	L.Push(lua.LString(body))
+	L.Push(lua.LString(err))
+
+	return 5
+}
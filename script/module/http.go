@@ -3,13 +3,11 @@ package script
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"time"
 
-	"github.com/pojol/gobot/utils"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -21,11 +19,21 @@ type Report struct {
 	ResBody int
 	Consume int
 	Err     string
+
+	Stream     bool // 流式请求（Consume 为整个流的持续时间
+	FirstEvent int  // 流式请求收到第一条消息的耗时（-1 没有收到消息
 }
 
 type HttpModule struct {
 	repolst []Report
 	client  *http.Client
+
+	tlsClients map[string]*http.Client // 按 tls 选项缓存的 client
+	session    httpSession
+	options    *HttpOptions  // 通过 Configure 设置的连接配置
+	streams    []*httpStream // 还没有结束的流式请求
+
+	authorize func(L *lua.LState, req *http.Request) error // auth 模块注入 token
 }
 
 func NewHttpModule() *HttpModule {
@@ -35,16 +43,55 @@ func NewHttpModule() *HttpModule {
 
 	client := &http.Client{
 		Transport: transport,
-		Timeout:   time.Second * 120,
+		Timeout:   time.Second * defaultHttpTimeout,
 	}
 
 	return NewHttpModuleWithDo(client)
 }
 
 func NewHttpModuleWithDo(client *http.Client) *HttpModule {
-	return &HttpModule{
-		client: client,
+	h := &HttpModule{
+		client:     client,
+		tlsClients: make(map[string]*http.Client),
+		session:    newHttpSession(),
+	}
+
+	if client.CheckRedirect == nil {
+		client.CheckRedirect = h.checkRedirect
+	}
+
+	return h
+}
+
+// tlsClient 获取使用指定 tls 配置的 client（transport 会被缓存复用
+func (h *HttpModule) tlsClient(opts *tlsOptions) (*http.Client, error) {
+	key := opts.key()
+	if c, ok := h.tlsClients[key]; ok {
+		return c, nil
+	}
+
+	conf, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+
+	var transport *http.Transport
+	if t, ok := h.client.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = &http.Transport{}
 	}
+	transport.TLSClientConfig = conf
+
+	c := &http.Client{
+		Transport:     transport,
+		Timeout:       h.client.Timeout,
+		Jar:           h.client.Jar,
+		CheckRedirect: h.client.CheckRedirect,
+	}
+	h.tlsClients[key] = c
+
+	return c, nil
 }
 
 func (h *HttpModule) Loader(L *lua.LState) int {
@@ -53,8 +100,15 @@ func (h *HttpModule) Loader(L *lua.LState) int {
 		"post":    h.post,
 		"put":     h.put,
 		"request": h.request,
+		"stream":  h.stream,
+		"graphql": h.graphql,
+
+		"session":       h.sessionSet,
+		"cookies":       h.sessionCookies,
+		"clear_session": h.sessionClear,
 	})
 	registerHttpResponseType(mod, L)
+	registerHttpStreamType(mod, L)
 	L.Push(mod)
 	return 1
 }
@@ -75,19 +129,26 @@ func (h *HttpModule) request(L *lua.LState) int {
 	return h.doRequestAndPush(L, L.ToString(1), L.ToString(2), L.ToTable(3))
 }
 
-func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options *lua.LTable) (*lua.LUserData, error) {
+// newRequest 根据选项构建请求，返回使用的 client 和请求体的长度（cancel 需要在请求结束后调用
+func (h *HttpModule) newRequest(L *lua.LState, method string, url string, options *lua.LTable) (*http.Client, *http.Request, int, context.CancelFunc, error) {
 
+	var reqlen int
+	cancel := func() {}
+	client := h.client
 	req, err := http.NewRequest(method, url, nil)
-	var reqlen, reslen int
 	if err != nil {
 		fmt.Printf("new request %v err : %v\n", method, err.Error())
-		return nil, err
+		return nil, nil, 0, cancel, err
 	}
 
 	if ctx := L.Context(); ctx != nil {
 		req = req.WithContext(ctx)
 	}
 
+	for k, v := range h.session.headers {
+		req.Header.Set(k, v)
+	}
+
 	if options != nil {
 		if reqCookies, ok := options.RawGet(lua.LString("cookies")).(*lua.LTable); ok {
 			reqCookies.ForEach(func(key lua.LValue, value lua.LValue) {
@@ -100,27 +161,18 @@ func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options
 			req.URL.RawQuery = reqQuery.String()
 		}
 
-		body := options.RawGet(lua.LString("body"))
-
-		switch reqBody := body.(type) {
-		case *lua.LTable:
-			m, err := utils.Table2Map(reqBody)
-			if err != nil {
-				fmt.Println("table 2 map err", err.Error())
-				return nil, err
-			}
-			byt, err := json.Marshal(m)
-			if err != nil {
-				fmt.Println("ltable marshal err", err.Error())
-				return nil, err
-			}
+		byt, contentType, ok, err := requestBody(options)
+		if err != nil {
+			fmt.Println("request body err", err.Error())
+			return nil, nil, 0, cancel, err
+		}
+		if ok {
 			reqlen = len(byt)
 			req.Body = ioutil.NopCloser(bytes.NewReader(byt))
-			req.Header.Set("Content-Type", "application/json")
-		case lua.LString:
-			reqlen = len(reqBody.String())
-			req.Body = ioutil.NopCloser(bytes.NewBufferString(reqBody.String()))
-			req.Header.Set("Content-Type", "application/json")
+			req.ContentLength = int64(reqlen)
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
 		}
 
 		reqTimeout := options.RawGet(lua.LString("timeout"))
@@ -133,12 +185,12 @@ func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options
 				duration, err = time.ParseDuration(string(reqTimeout))
 				if err != nil {
 					fmt.Printf("parse timeout err %v\n", err.Error())
-					return nil, err
+					return nil, nil, 0, cancel, err
 				}
 			}
-			ctx, cancel := context.WithTimeout(req.Context(), duration)
+			var ctx context.Context
+			ctx, cancel = context.WithTimeout(req.Context(), duration)
 			req = req.WithContext(ctx)
-			defer cancel()
 		}
 
 		// Basic auth
@@ -148,7 +200,21 @@ func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options
 			if !lua.LVIsFalse(user) && !lua.LVIsFalse(pass) {
 				req.SetBasicAuth(user.String(), pass.String())
 			} else {
-				return nil, fmt.Errorf("auth table must contain no nil user and pass fields")
+				return nil, nil, 0, cancel, fmt.Errorf("auth table must contain no nil user and pass fields")
+			}
+		}
+
+		if redirect := options.RawGetString("redirect"); redirect != lua.LNil {
+			if max, ok := parseRedirect(redirect); ok {
+				req = req.WithContext(context.WithValue(req.Context(), redirectKey{}, max))
+			}
+		}
+
+		if tlsopts, ok := parseTLSOptions(options); ok {
+			client, err = h.tlsClient(tlsopts)
+			if err != nil {
+				fmt.Printf("tls config err %v\n", err.Error())
+				return nil, nil, 0, cancel, err
 			}
 		}
 
@@ -160,18 +226,48 @@ func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options
 		}
 	}
 
+	if h.authorize != nil {
+		err = h.authorize(L, req)
+		if err != nil {
+			return nil, nil, 0, cancel, err
+		}
+	}
+
+	return client, req, reqlen, cancel, nil
+}
+
+func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options *lua.LTable) (*lua.LUserData, error) {
+
+	url = h.resolveURL(url)
+	client, req, reqlen, cancel, err := h.newRequest(L, method, url, options)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	res, body, inf, err := h.do(client, req, url, reqlen)
+	h.repolst = append(h.repolst, inf)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHttpResponse(res, &body, len(body), L), nil
+}
+
+// do 发送请求并读取整个响应，api 为写入报告时使用的名字（报告由调用方写入
+func (h *HttpModule) do(client *http.Client, req *http.Request, api string, reqlen int) (*http.Response, []byte, Report, error) {
+
 	cur := time.Now()
 	inf := Report{
-		Api:     url,
+		Api:     api,
 		ReqBody: reqlen,
 	}
 
-	res, err := h.client.Do(req)
+	res, err := client.Do(req)
 	if err != nil {
 		err = fmt.Errorf("client do err : %v", err.Error())
 		inf.Err = err.Error()
-		h.repolst = append(h.repolst, inf)
-		return nil, err
+		return nil, nil, inf, err
 	}
 
 	defer res.Body.Close()
@@ -179,16 +275,13 @@ func (h *HttpModule) doRequest(L *lua.LState, method string, url string, options
 	if err != nil {
 		err = fmt.Errorf("read body err : %v", err.Error())
 		inf.Err = err.Error()
-		h.repolst = append(h.repolst, inf)
-		return nil, err
+		return nil, nil, inf, err
 	}
 
-	reslen = len(body)
-	inf.ResBody = reslen
+	inf.ResBody = len(body)
 	inf.Consume = int(time.Since(cur).Milliseconds())
 
-	h.repolst = append(h.repolst, inf)
-	return newHttpResponse(res, &body, reslen, L), nil
+	return res, body, inf, nil
 }
 
 func (h *HttpModule) doRequestAndPush(L *lua.LState, method string, url string, options *lua.LTable) int {
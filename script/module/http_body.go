@@ -0,0 +1,139 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+
+	"github.com/pojol/gobot/utils"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// requestBody 解析请求体相关的选项，返回 body 内容和 content type（为空时不设置
+//
+//	body = {} / ""      application/json
+//	form = {}           application/x-www-form-urlencoded
+//	multipart = {       multipart/form-data，文件从脚本目录读取
+//	    name = "joy",
+//	    avatar = { file = "avatar.png", filename = "a.png", content_type = "image/png" },
+//	}
+//	bytes = ""          原始二进制，不设置 content type
+//	proto = ""          proto.marshal 的结果，application/x-protobuf
+func requestBody(options *lua.LTable) ([]byte, string, bool, error) {
+
+	switch reqBody := options.RawGetString("body").(type) {
+	case *lua.LTable:
+		m, err := utils.Table2Map(reqBody)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("table 2 map err %w", err)
+		}
+		byt, err := json.Marshal(m)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("ltable marshal err %w", err)
+		}
+		return byt, "application/json", true, nil
+	case lua.LString:
+		return []byte(reqBody), "application/json", true, nil
+	}
+
+	if form, ok := options.RawGetString("form").(*lua.LTable); ok {
+		values := url.Values{}
+		form.ForEach(func(k, v lua.LValue) {
+			if arr, ok := v.(*lua.LTable); ok {
+				for i := 1; i <= arr.Len(); i++ {
+					values.Add(k.String(), arr.RawGetInt(i).String())
+				}
+			} else {
+				values.Add(k.String(), v.String())
+			}
+		})
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", true, nil
+	}
+
+	if parts, ok := options.RawGetString("multipart").(*lua.LTable); ok {
+		return multipartBody(parts)
+	}
+
+	if raw, ok := options.RawGetString("bytes").(lua.LString); ok {
+		return []byte(raw), "", true, nil
+	}
+
+	if pb, ok := options.RawGetString("proto").(lua.LString); ok {
+		return []byte(pb), "application/x-protobuf", true, nil
+	}
+
+	return nil, "", false, nil
+}
+
+func multipartBody(parts *lua.LTable) ([]byte, string, bool, error) {
+	var buf bytes.Buffer
+	var err error
+	w := multipart.NewWriter(&buf)
+
+	parts.ForEach(func(k, v lua.LValue) {
+		if err != nil {
+			return
+		}
+
+		field := k.String()
+		part, ok := v.(*lua.LTable)
+		if !ok {
+			err = w.WriteField(field, v.String())
+			return
+		}
+
+		err = writeFilePart(w, field, part)
+	})
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), true, nil
+}
+
+// writeFilePart 文件内容可以来自脚本目录下的文件（file），也可以直接传入（content
+func writeFilePart(w *multipart.Writer, field string, part *lua.LTable) error {
+	var content []byte
+	var err error
+
+	file := lua.LVAsString(part.RawGetString("file"))
+	if file != "" {
+		content, err = readScriptFile(file)
+		if err != nil {
+			return fmt.Errorf("multipart read file err %w", err)
+		}
+	} else {
+		content = []byte(lua.LVAsString(part.RawGetString("content")))
+	}
+
+	filename := lua.LVAsString(part.RawGetString("filename"))
+	if filename == "" {
+		filename = filepath.Base(file)
+	}
+
+	contentType := lua.LVAsString(part.RawGetString("content_type"))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, filename))
+	h.Set("Content-Type", contentType)
+
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	_, err = pw.Write(content)
+	return err
+}
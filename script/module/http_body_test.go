@@ -0,0 +1,86 @@
+package script
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestHttpBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/form":
+			if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			req.ParseForm()
+			w.Write([]byte(req.PostForm.Get("name") + " " + req.PostForm["tag"][1]))
+		case "/multipart":
+			err := req.ParseMultipartForm(1 << 20)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f, fh, err := req.FormFile("avatar")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			defer f.Close()
+			content, _ := ioutil.ReadAll(f)
+			w.Write([]byte(req.FormValue("name") + " " + fh.Filename + " " + string(content)))
+		case "/bytes":
+			byt, _ := ioutil.ReadAll(req.Body)
+			w.Write([]byte(req.Header.Get("Content-Type") + "|" + string(byt)))
+		case "/proto":
+			byt, _ := ioutil.ReadAll(req.Body)
+			w.Write([]byte(req.Header.Get("Content-Type") + "|" + string(byt)))
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "avatar.png"), []byte("png"), 0644)
+	assert.Equal(t, err, nil)
+	SetScriptPath(dir)
+	defer SetScriptPath("script/")
+
+	httpMod := NewHttpModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+	L.SetGlobal("url", lua.LString(ts.URL))
+
+	err = L.DoString(`
+		local http = require("http")
+
+		local res, err = http.post(url .. "/form", {form = {name = "joy", tag = {"a", "b"}}})
+		assert(err == nil, err)
+		assert(res["body"] == "joy b", res["body"])
+
+		res, err = http.post(url .. "/multipart", {multipart = {
+			name = "joy",
+			avatar = {file = "avatar.png", content_type = "image/png"},
+		}})
+		assert(err == nil, err)
+		assert(res["body"] == "joy avatar.png png", res["body"])
+
+		res, err = http.post(url .. "/bytes", {bytes = "\x01\x02"})
+		assert(err == nil, err)
+		assert(res["body"] == "|\x01\x02", res["body"])
+
+		res, err = http.post(url .. "/proto", {proto = "\x08\x01"})
+		assert(err == nil, err)
+		assert(res["body"] == "application/x-protobuf|\x08\x01", res["body"])
+	`)
+	assert.Equal(t, err, nil)
+}
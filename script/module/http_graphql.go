@@ -0,0 +1,118 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/pojol/gobot/utils"
+	lua "github.com/yuin/gopher-lua"
+)
+
+var graphqlOperationRe = regexp.MustCompile(`^\s*(?:#[^\n]*\n\s*)*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// graphqlOperation 从 query 中解析操作名（匿名的 query 返回空
+func graphqlOperation(query string) string {
+	m := graphqlOperationRe.FindStringSubmatch(query)
+	if len(m) != 2 {
+		return ""
+	}
+	return m[1]
+}
+
+type graphqlResponse struct {
+	Data   interface{}   `json:"data"`
+	Errors []interface{} `json:"errors"`
+}
+
+// graphql(url, query, variables, opts) 返回 data, errors
+//
+//	local data, errors = http.graphql(url, [[
+//	    query GetUser($id: ID!) { user(id: $id) { name } }
+//	]], { id = "1" }, { headers = {} })
+//
+// opts 与 http.post 相同，另外可以通过 operation 指定操作名；报告中以 url#操作名 统计
+// 请求失败时返回 nil, nil, err
+func (h *HttpModule) graphql(L *lua.LState) int {
+	url := h.resolveURL(L.CheckString(1))
+	query := L.CheckString(2)
+	variables := L.ToTable(3)
+	opts := L.ToTable(4)
+
+	payload := map[string]interface{}{
+		"query": query,
+	}
+
+	if variables != nil {
+		m, err := utils.Table2MgoMap(variables)
+		if err != nil {
+			return pushGraphqlErr(L, fmt.Errorf("variables %w", err))
+		}
+		payload["variables"] = m
+	}
+
+	op := graphqlOperation(query)
+	reqopts := L.NewTable()
+	if opts != nil {
+		opts.ForEach(func(k, v lua.LValue) {
+			reqopts.RawSet(k, v)
+		})
+		if v, ok := opts.RawGetString("operation").(lua.LString); ok {
+			op = string(v)
+		}
+	}
+	if op != "" {
+		payload["operationName"] = op
+	}
+
+	byt, err := json.Marshal(payload)
+	if err != nil {
+		return pushGraphqlErr(L, err)
+	}
+	reqopts.RawSetString("body", lua.LString(byt))
+
+	client, req, reqlen, cancel, err := h.newRequest(L, "POST", url, reqopts)
+	defer cancel()
+	if err != nil {
+		return pushGraphqlErr(L, err)
+	}
+
+	api := url
+	if op != "" {
+		api = url + "#" + op
+	}
+
+	res, body, inf, err := h.do(client, req, api, reqlen)
+	if err != nil {
+		h.repolst = append(h.repolst, inf)
+		return pushGraphqlErr(L, err)
+	}
+
+	ret := graphqlResponse{}
+	err = json.Unmarshal(body, &ret)
+	if err != nil {
+		err = fmt.Errorf("graphql response status %v err : %v", res.StatusCode, err.Error())
+		inf.Err = err.Error()
+		h.repolst = append(h.repolst, inf)
+		return pushGraphqlErr(L, err)
+	}
+
+	if len(ret.Errors) != 0 {
+		inf.Err = fmt.Sprint(ret.Errors[0])
+		if e, ok := ret.Errors[0].(map[string]interface{}); ok {
+			inf.Err = fmt.Sprint(e["message"])
+		}
+	}
+	h.repolst = append(h.repolst, inf)
+
+	L.Push(utils.ToLuaValue(L, ret.Data))
+	L.Push(utils.ToLuaValue(L, append([]interface{}{}, ret.Errors...)))
+	return 2
+}
+
+func pushGraphqlErr(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LNil)
+	L.Push(lua.LString(fmt.Sprintf("%s", err)))
+	return 3
+}
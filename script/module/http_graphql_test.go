@@ -0,0 +1,73 @@
+package script
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestGraphqlOperation(t *testing.T) {
+	assert.Equal(t, graphqlOperation(`query GetUser($id: ID!) { user(id: $id) { name } }`), "GetUser")
+	assert.Equal(t, graphqlOperation("# comment\n  mutation AddItem { add }"), "AddItem")
+	assert.Equal(t, graphqlOperation(`{ user { name } }`), "")
+}
+
+func TestHttpGraphql(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload := struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+		}{}
+		json.NewDecoder(req.Body).Decode(&payload)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch payload.OperationName {
+		case "GetUser":
+			w.Write([]byte(`{"data":{"user":{"id":"` + payload.Variables["userId"].(string) + `","name":"joy"}}}`))
+		case "AddItem":
+			w.Write([]byte(`{"data":null,"errors":[{"message":"item not found","path":["add"]}]}`))
+		}
+	}))
+	defer ts.Close()
+
+	httpMod := NewHttpModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+	L.SetGlobal("url", lua.LString(ts.URL+"/graphql"))
+
+	err := L.DoString(`
+		local http = require("http")
+
+		local data, errors, err = http.graphql(url, [[
+			query GetUser($userId: ID!) { user(id: $userId) { id name } }
+		]], { userId = "u001" })
+		assert(err == nil, err)
+		assert(#errors == 0, "errors")
+		assert(data.user.id == "u001" and data.user.name == "joy", "data")
+
+		data, errors, err = http.graphql(url, "mutation AddItem { add }")
+		assert(err == nil, err)
+		assert(data == nil, "nil data")
+		assert(errors[1].message == "item not found", "errors message")
+
+		data, errors, err = http.graphql("http://127.0.0.1:1/graphql", "{ user { id } }")
+		assert(data == nil and errors == nil and err ~= nil, "request err")
+	`)
+	assert.Equal(t, err, nil)
+
+	rep := httpMod.GetReport()
+	assert.Equal(t, len(rep), 3)
+	assert.Equal(t, rep[0].Api, ts.URL+"/graphql#GetUser")
+	assert.Equal(t, rep[0].Err, "")
+	assert.Equal(t, rep[1].Api, ts.URL+"/graphql#AddItem")
+	assert.Equal(t, rep[1].Err, "item not found")
+	assert.Equal(t, rep[2].Api, "http://127.0.0.1:1/graphql")
+}
@@ -0,0 +1,196 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// 默认的重定向次数（与 net/http 的默认策略一致
+const defaultMaxRedirects = 10
+
+type redirectKey struct{}
+
+// httpSession 单个 bot 的 http 会话状态（cookie jar，重定向策略，默认的 headers 和 base url
+type httpSession struct {
+	jar          http.CookieJar
+	maxRedirects int
+	baseURL      string
+	headers      map[string]string
+}
+
+func newHttpSession() httpSession {
+	return httpSession{
+		maxRedirects: defaultMaxRedirects,
+		headers:      make(map[string]string),
+	}
+}
+
+// parseRedirect 解析重定向选项 "follow" / "none" / 最大重定向次数
+func parseRedirect(v lua.LValue) (int, bool) {
+	switch v := v.(type) {
+	case lua.LNumber:
+		return int(v), true
+	case lua.LBool:
+		if bool(v) {
+			return defaultMaxRedirects, true
+		}
+		return 0, true
+	case lua.LString:
+		switch string(v) {
+		case "follow":
+			return defaultMaxRedirects, true
+		case "none":
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
+
+func (h *HttpModule) checkRedirect(req *http.Request, via []*http.Request) error {
+	max := h.session.maxRedirects
+	if v, ok := req.Context().Value(redirectKey{}).(int); ok {
+		max = v
+	}
+
+	if max <= 0 {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= max {
+		return fmt.Errorf("stopped after %d redirects", max)
+	}
+
+	return nil
+}
+
+// setJar 更新所有 client 的 cookie jar
+func (h *HttpModule) setJar(jar http.CookieJar) {
+	h.session.jar = jar
+	h.client.Jar = jar
+	for _, c := range h.tlsClients {
+		c.Jar = jar
+	}
+}
+
+// resolveURL 相对地址会拼接在 base_url 之后
+func (h *HttpModule) resolveURL(u string) string {
+	if h.session.baseURL == "" || strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+
+	return strings.TrimRight(h.session.baseURL, "/") + "/" + strings.TrimLeft(u, "/")
+}
+
+// session 设置当前 bot 的 http 会话
+//
+//	http.session({
+//	    cookiejar = true,           -- 开启 cookie jar，响应中的 cookie 会在后续请求中自动带上
+//	    redirect = "follow",        -- "follow" / "none" / 最大重定向次数
+//	    base_url = "http://127.0.0.1:8888",
+//	    headers = { Authorization = "Bearer xxx" },
+//	})
+func (h *HttpModule) sessionSet(L *lua.LState) int {
+	err := h._sessionSet(L.CheckTable(1))
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (h *HttpModule) _sessionSet(opts *lua.LTable) error {
+
+	switch v := opts.RawGetString("cookiejar").(type) {
+	case lua.LBool:
+		if bool(v) && h.session.jar == nil {
+			jar, err := cookiejar.New(nil)
+			if err != nil {
+				return err
+			}
+			h.setJar(jar)
+		} else if !bool(v) {
+			h.setJar(nil)
+		}
+	}
+
+	if redirect := opts.RawGetString("redirect"); redirect != lua.LNil {
+		max, ok := parseRedirect(redirect)
+		if !ok {
+			return errors.New("redirect must be \"follow\", \"none\" or a number")
+		}
+		h.session.maxRedirects = max
+	}
+
+	if baseURL, ok := opts.RawGetString("base_url").(lua.LString); ok {
+		if baseURL != "" {
+			if _, err := url.Parse(string(baseURL)); err != nil {
+				return err
+			}
+		}
+		h.session.baseURL = string(baseURL)
+	}
+
+	if headers, ok := opts.RawGetString("headers").(*lua.LTable); ok {
+		headers.ForEach(func(k, v lua.LValue) {
+			h.session.headers[k.String()] = v.String()
+		})
+	}
+
+	return nil
+}
+
+// cookies(url) 获取 cookie jar 中 url 对应的 cookie
+func (h *HttpModule) sessionCookies(L *lua.LState) int {
+	cookies := L.NewTable()
+
+	if h.session.jar == nil {
+		L.Push(cookies)
+		return 1
+	}
+
+	u, err := url.Parse(h.resolveURL(L.CheckString(1)))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 2
+	}
+
+	for _, c := range h.session.jar.Cookies(u) {
+		cookies.RawSetString(c.Name, lua.LString(c.Value))
+	}
+
+	L.Push(cookies)
+	return 1
+}
+
+func (h *HttpModule) sessionClear(L *lua.LState) int {
+	h.clearSession()
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// Reset 清理 bot 的 http 会话（lua state 放回池中时调用，避免被下一个 bot 复用
+func (h *HttpModule) Reset() {
+	for len(h.streams) != 0 {
+		h.streams[0].close()
+	}
+	// 关闭流时写入的报告属于上一个 bot
+	h.repolst = h.repolst[:0]
+	h.closeIdle()
+	h.clearSession()
+}
+
+// clearSession 清理 cookie jar、base_url 和公共 header（报告、流和连接不受影响
+func (h *HttpModule) clearSession() {
+	h.setJar(nil)
+	h.session = newHttpSession()
+}
@@ -0,0 +1,81 @@
+package script
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestHttpSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s001", Path: "/"})
+			w.Write([]byte("login"))
+		case "/redirect":
+			http.Redirect(w, req, "/profile", http.StatusFound)
+		case "/profile":
+			c, err := req.Cookie("sid")
+			if err != nil || req.Header.Get("X-Client") != "gobot" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("profile " + c.Value))
+		}
+	}))
+	defer ts.Close()
+
+	httpMod := NewHttpModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+	L.SetGlobal("url", lua.LString(ts.URL))
+
+	err := L.DoString(`
+		local http = require("http")
+
+		local ret = http.session({
+			cookiejar = true,
+			base_url = url,
+			headers = { ["X-Client"] = "gobot" },
+		})
+		assert(ret == "succ", ret)
+
+		local res, err = http.get("/login")
+		assert(err == nil, err)
+		assert(res["cookies"].sid == "s001", "response cookies")
+		assert(http.cookies("/").sid == "s001", "jar cookies")
+
+		res, err = http.get("/redirect")
+		assert(res["status_code"] == 200, "follow redirect " .. res["status_code"])
+		assert(res["body"] == "profile s001", res["body"])
+
+		res, err = http.get("/redirect", {redirect = "none"})
+		assert(res["status_code"] == 302, "no redirect " .. res["status_code"])
+	`)
+	assert.Equal(t, err, nil)
+
+	// clear_session 只清理会话，之前请求的报告仍然保留
+	err = L.DoString(`
+		local http = require("http")
+		assert(http.clear_session() == "succ")
+		local res, err = http.get(url .. "/profile")
+		assert(res["status_code"] == 401, "clear session " .. res["status_code"])
+	`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(httpMod.GetReport()), 4)
+
+	// the session must not leak into the next bot which reuses the state
+	httpMod.Reset()
+	err = L.DoString(`
+		local http = require("http")
+		local res, err = http.get(url .. "/profile")
+		assert(res["status_code"] == 401, "reset session " .. res["status_code"])
+	`)
+	assert.Equal(t, err, nil)
+}
@@ -0,0 +1,364 @@
+package script
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const luaHttpStreamTypeName = "http.stream"
+
+// 单次 poll 默认取出的消息数量
+const defaultStreamPoll = 16
+
+type streamEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry int
+}
+
+// httpStream 流式的 http 响应（sse / chunked），由后台协程读取，脚本在每次 tick 中通过 poll 非阻塞的取出
+type httpStream struct {
+	h      *HttpModule
+	res    *http.Response
+	cancel context.CancelFunc
+	sse    bool
+	events chan streamEvent
+
+	sync.Mutex
+	begin time.Time
+	first time.Duration // 收到第一条消息的耗时（-1 还没有收到
+	size  int
+	err   error
+	eof   bool
+
+	finished bool // 消息已经全部取出，并且已经写入报告
+	inf      Report
+}
+
+func registerHttpStreamType(module *lua.LTable, L *lua.LState) {
+	mt := L.NewTypeMetatable(luaHttpStreamTypeName)
+	L.SetField(mt, "__index", L.NewFunction(httpStreamIndex))
+
+	L.SetField(module, "stream_type", mt)
+}
+
+// stream(method, url, opts) 发起一个流式请求，返回 stream 句柄
+//
+//	local s, err = http.stream("GET", url, { sse = true })
+//	local events = s:poll(10)   -- { {id = "", event = "", data = "", retry = 0}, ... }
+//	if s:done() then s:close() end
+//
+// sse 为空时根据响应的 Content-Type 判断，不是 sse 的流每次读取到的数据作为一条消息（只有 data 字段
+func (h *HttpModule) stream(L *lua.LState) int {
+	method := L.CheckString(1)
+	url := h.resolveURL(L.CheckString(2))
+	options := L.ToTable(3)
+
+	client, req, reqlen, cancel, err := h.newRequest(L, method, url, options)
+	if err != nil {
+		cancel()
+		return pushErr(L, err)
+	}
+
+	// 读取流的过程不受 client 整体超时的限制（需要限制时使用 timeout 选项
+	sc := *client
+	sc.Timeout = 0
+
+	ctx, streamCancel := context.WithCancel(req.Context())
+	req = req.WithContext(ctx)
+
+	sse, sseSet := false, false
+	if options != nil {
+		if v, ok := options.RawGetString("sse").(lua.LBool); ok {
+			sse, sseSet = bool(v), true
+		}
+	}
+	if sse && req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	s := &httpStream{
+		h: h,
+		cancel: func() {
+			streamCancel()
+			cancel()
+		},
+		events: make(chan streamEvent, 1024),
+		begin:  time.Now(),
+		first:  -1,
+		inf: Report{
+			Api:        url,
+			ReqBody:    reqlen,
+			Stream:     true,
+			FirstEvent: -1,
+		},
+	}
+
+	res, err := sc.Do(req)
+	if err != nil {
+		s.cancel()
+		err = fmt.Errorf("client do err : %v", err.Error())
+		s.inf.Err = err.Error()
+		h.repolst = append(h.repolst, s.inf)
+		return pushErr(L, err)
+	}
+
+	if !sseSet {
+		sse = strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream")
+	}
+
+	s.res = res
+	s.sse = sse
+	h.streams = append(h.streams, s)
+
+	go s.read()
+
+	ud := L.NewUserData()
+	ud.Value = s
+	L.SetMetatable(ud, L.GetTypeMetatable(luaHttpStreamTypeName))
+	L.Push(ud)
+	return 1
+}
+
+func (s *httpStream) push(ev streamEvent, n int) bool {
+	s.Lock()
+	if s.first < 0 {
+		s.first = time.Since(s.begin)
+	}
+	s.size += n
+	s.Unlock()
+
+	select {
+	case s.events <- ev:
+		return true
+	case <-s.done():
+		return false
+	}
+}
+
+func (s *httpStream) done() <-chan struct{} {
+	return s.res.Request.Context().Done()
+}
+
+func (s *httpStream) read() {
+	defer close(s.events)
+	defer s.res.Body.Close()
+
+	var err error
+	if s.sse {
+		err = s.readEvents()
+	} else {
+		err = s.readChunks()
+	}
+
+	s.Lock()
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) {
+		s.err = err
+	}
+	s.eof = true
+	s.Unlock()
+}
+
+func (s *httpStream) readChunks() error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.res.Body.Read(buf)
+		if n > 0 {
+			if !s.push(streamEvent{Data: string(buf[:n])}, n) {
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readEvents 按照 text/event-stream 的格式解析消息（空行表示一条消息结束
+func (s *httpStream) readEvents() error {
+	reader := bufio.NewReader(s.res.Body)
+
+	ev := streamEvent{}
+	data := []string{}
+	size := 0
+
+	for {
+		line, err := reader.ReadString('\n')
+		size += len(line)
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" && err == nil {
+			if len(data) != 0 {
+				ev.Data = strings.Join(data, "\n")
+				if !s.push(ev, size) {
+					return nil
+				}
+			}
+			ev, data, size = streamEvent{}, data[:0], 0
+			continue
+		}
+
+		if line != "" && !strings.HasPrefix(line, ":") {
+			field, value := line, ""
+			if idx := strings.Index(line, ":"); idx >= 0 {
+				field, value = line[:idx], strings.TrimPrefix(line[idx+1:], " ")
+			}
+
+			switch field {
+			case "id":
+				ev.ID = value
+			case "event":
+				ev.Event = value
+			case "data":
+				data = append(data, value)
+			case "retry":
+				ev.Retry, _ = strconv.Atoi(value)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// finish 流结束后写入报告（只写入一次
+func (s *httpStream) finish() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+
+	s.Lock()
+	s.inf.ResBody = s.size
+	s.inf.Consume = int(time.Since(s.begin).Milliseconds())
+	if s.first >= 0 {
+		s.inf.FirstEvent = int(s.first.Milliseconds())
+	}
+	if s.err != nil {
+		s.inf.Err = s.err.Error()
+	}
+	s.Unlock()
+
+	s.h.repolst = append(s.h.repolst, s.inf)
+
+	for k, v := range s.h.streams {
+		if v == s {
+			s.h.streams = append(s.h.streams[:k], s.h.streams[k+1:]...)
+			break
+		}
+	}
+}
+
+// close 主动关闭流，未取出的消息会被丢弃
+func (s *httpStream) close() {
+	s.cancel()
+	for range s.events {
+	}
+	s.finish()
+}
+
+func checkHttpStream(L *lua.LState) *httpStream {
+	ud := L.CheckUserData(1)
+	if v, ok := ud.Value.(*httpStream); ok {
+		return v
+	}
+	L.ArgError(1, "http.stream expected")
+	return nil
+}
+
+func httpStreamIndex(L *lua.LState) int {
+	s := checkHttpStream(L)
+
+	switch L.CheckString(2) {
+	case "status_code":
+		L.Push(lua.LNumber(s.res.StatusCode))
+	case "headers":
+		headers := L.NewTable()
+		for key := range s.res.Header {
+			headers.RawSetString(key, lua.LString(s.res.Header.Get(key)))
+		}
+		L.Push(headers)
+	case "poll":
+		L.Push(L.NewFunction(httpStreamPoll))
+	case "done":
+		L.Push(L.NewFunction(httpStreamDone))
+	case "close":
+		L.Push(L.NewFunction(httpStreamClose))
+	default:
+		return 0
+	}
+
+	return 1
+}
+
+// poll(max) 非阻塞的取出已经收到的消息，没有消息时返回空表
+func httpStreamPoll(L *lua.LState) int {
+	s := checkHttpStream(L)
+	max := L.OptInt(2, defaultStreamPoll)
+
+	tbl := L.NewTable()
+	for i := 0; i < max; i++ {
+		select {
+		case ev, ok := <-s.events:
+			if !ok {
+				s.finish()
+				L.Push(tbl)
+				return 1
+			}
+
+			item := L.CreateTable(0, 4)
+			item.RawSetString("data", lua.LString(ev.Data))
+			if s.sse {
+				item.RawSetString("id", lua.LString(ev.ID))
+				item.RawSetString("event", lua.LString(ev.Event))
+				item.RawSetString("retry", lua.LNumber(ev.Retry))
+			}
+			tbl.Append(item)
+		default:
+			L.Push(tbl)
+			return 1
+		}
+	}
+
+	L.Push(tbl)
+	return 1
+}
+
+// done() 流已经结束并且消息全部取出后返回 true，第二个返回值为读取过程中的错误
+func httpStreamDone(L *lua.LState) int {
+	s := checkHttpStream(L)
+
+	s.Lock()
+	eof := s.eof
+	s.Unlock()
+	if eof && len(s.events) == 0 {
+		s.finish()
+	}
+
+	L.Push(lua.LBool(s.finished))
+	if s.inf.Err != "" {
+		L.Push(lua.LString(s.inf.Err))
+		return 2
+	}
+	return 1
+}
+
+func httpStreamClose(L *lua.LState) int {
+	s := checkHttpStream(L)
+	s.close()
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
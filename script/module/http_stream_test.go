@@ -0,0 +1,117 @@
+package script
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestHttpStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		flusher := w.(http.Flusher)
+
+		switch req.URL.Path {
+		case "/sse":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(w, ": ping\nid: %d\nevent: token\ndata: hello\ndata: %d\n\n", i, i)
+				flusher.Flush()
+				time.Sleep(time.Millisecond * 10)
+			}
+		case "/chunk":
+			for i := 0; i < 3; i++ {
+				w.Write([]byte("chunk"))
+				flusher.Flush()
+				time.Sleep(time.Millisecond * 10)
+			}
+		case "/forever":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for {
+				_, err := fmt.Fprintf(w, "data: tick\n\n")
+				if err != nil {
+					return
+				}
+				flusher.Flush()
+				time.Sleep(time.Millisecond * 10)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	httpMod := NewHttpModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+	L.SetGlobal("url", lua.LString(ts.URL))
+	L.SetGlobal("sleep", L.NewFunction(func(L *lua.LState) int {
+		time.Sleep(time.Millisecond * 5)
+		return 0
+	}))
+
+	err := L.DoString(`
+		local http = require("http")
+
+		local s, err = http.stream("GET", url .. "/sse")
+		assert(err == nil, err)
+		assert(s.status_code == 200, "status code")
+
+		local events = {}
+		while not s:done() do
+			for _, ev in ipairs(s:poll()) do
+				table.insert(events, ev)
+			end
+			sleep()
+		end
+		assert(#events == 3, "sse events " .. #events)
+		assert(events[2].id == "2" and events[2].event == "token", "sse fields")
+		assert(events[3].data == "hello\n3", events[3].data)
+
+		s, err = http.stream("GET", url .. "/chunk")
+		assert(err == nil, err)
+		local body = ""
+		while not s:done() do
+			for _, ev in ipairs(s:poll()) do
+				body = body .. ev.data
+			end
+			sleep()
+		end
+		assert(body == "chunkchunkchunk", body)
+
+		s, err = http.stream("GET", url .. "/forever", {sse = true})
+		assert(err == nil, err)
+		while #s:poll() == 0 do
+			sleep()
+		end
+		assert(s:close() == "succ")
+		assert(s:done())
+	`)
+	assert.Equal(t, err, nil)
+
+	rep := httpMod.GetReport()
+	assert.Equal(t, len(rep), 3)
+	for _, v := range rep {
+		assert.True(t, v.Stream)
+		assert.True(t, v.FirstEvent >= 0)
+		assert.True(t, v.Consume >= v.FirstEvent)
+		assert.Equal(t, v.Err, "")
+	}
+	assert.True(t, rep[0].Consume >= 20)
+
+	// 放回池中时还没有关闭的流被关闭，报告不会留给下一个 bot
+	err = L.DoString(`
+		local http = require("http")
+		opened = http.stream("GET", url .. "/forever", {sse = true})
+	`)
+	assert.Equal(t, err, nil)
+
+	httpMod.Reset()
+	assert.Equal(t, len(httpMod.GetReport()), 0)
+	assert.Equal(t, len(httpMod.streams), 0)
+}
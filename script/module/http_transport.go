@@ -0,0 +1,181 @@
+package script
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HttpOptions http 连接相关的配置，可以在 ConfTable 中全局设置，也可以在行为树的根节点中单独设置
+//
+//	<http>
+//	    <http2>true</http2>
+//	    <shared>true</shared>
+//	</http>
+type HttpOptions struct {
+	HTTP2               bool   `json:"http2" xml:"http2"`                                     // 尝试使用 http2
+	DisableKeepAlive    bool   `json:"disable_keepalive" xml:"disable_keepalive"`             // 每个请求都使用新的连接
+	MaxIdleConns        int    `json:"max_idle_conns" xml:"max_idle_conns"`                   // 最大空闲连接数（0 不限制
+	MaxIdleConnsPerHost int    `json:"max_idle_conns_per_host" xml:"max_idle_conns_per_host"` // 每个 host 的最大空闲连接数（0 使用默认值，独立的 transport 为 2，共享的为 1024
+	IdleTimeout         int    `json:"idle_timeout" xml:"idle_timeout"`                       // 空闲连接的超时时间（秒
+	Timeout             int    `json:"timeout" xml:"timeout"`                                 // 请求超时时间（秒，0 使用默认值 120
+	SourceIP            string `json:"source_ip" xml:"source_ip"`                             // 绑定的本地地址
+	Proxy               string `json:"proxy" xml:"proxy"`                                     // 代理地址 http://127.0.0.1:8080
+	Shared              bool   `json:"shared" xml:"shared"`                                   // 相同配置的 bot 共享同一个 transport（默认每个 bot 独立，模拟不同的客户端
+}
+
+const defaultHttpTimeout = 120
+
+// 共享的 transport 上每个 host 默认的空闲连接数（go 的默认值 2 会导致大量 bot 不断的新建连接
+const defaultSharedIdleConnsPerHost = 1024
+
+// ParseHttpOptions 解析 ConfTable 中保存的 json 配置，为空时返回默认配置
+func ParseHttpOptions(byt []byte) (HttpOptions, error) {
+	opts := HttpOptions{}
+	if len(byt) == 0 {
+		return opts, nil
+	}
+
+	err := json.Unmarshal(byt, &opts)
+	if err != nil {
+		return opts, err
+	}
+
+	_, err = opts.transport()
+	return opts, err
+}
+
+// ParseHttpOptionsXML 解析行为树根节点中 <http> 的内容
+func ParseHttpOptionsXML(inner []byte) (HttpOptions, error) {
+	opts := HttpOptions{}
+
+	err := xml.Unmarshal([]byte("<http>"+string(inner)+"</http>"), &opts)
+	if err != nil {
+		return opts, err
+	}
+
+	_, err = opts.transport()
+	return opts, err
+}
+
+func (o HttpOptions) key() string {
+	return fmt.Sprintf("%v", o)
+}
+
+func (o HttpOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return time.Second * defaultHttpTimeout
+	}
+	return time.Second * time.Duration(o.Timeout)
+}
+
+func (o HttpOptions) transport() (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if o.SourceIP != "" {
+		ip := net.ParseIP(o.SourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source ip %v", o.SourceIP)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	idlePerHost := o.MaxIdleConnsPerHost
+	if idlePerHost <= 0 && o.Shared {
+		idlePerHost = defaultSharedIdleConnsPerHost
+	}
+
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   o.HTTP2,
+		DisableKeepAlives:   o.DisableKeepAlive,
+		MaxIdleConns:        o.MaxIdleConns,
+		MaxIdleConnsPerHost: idlePerHost,
+		IdleConnTimeout:     time.Second * time.Duration(o.IdleTimeout),
+	}
+
+	if o.Proxy != "" {
+		u, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	return transport, nil
+}
+
+// 开启 shared 时，相同配置的 bot 共享同一个 transport（连接池
+var sharedTransports = struct {
+	sync.Mutex
+	m map[string]*http.Transport
+}{m: make(map[string]*http.Transport)}
+
+func sharedTransport(opts HttpOptions) (*http.Transport, error) {
+	sharedTransports.Lock()
+	defer sharedTransports.Unlock()
+
+	key := opts.key()
+	if t, ok := sharedTransports.m[key]; ok {
+		return t, nil
+	}
+
+	t, err := opts.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	sharedTransports.m[key] = t
+	return t, nil
+}
+
+// Configure 按照配置更新 bot 使用的 transport
+func (h *HttpModule) Configure(opts HttpOptions) error {
+	if opts.Shared && h.options != nil && h.options.key() == opts.key() {
+		return nil
+	}
+
+	var transport *http.Transport
+	var err error
+	if opts.Shared {
+		transport, err = sharedTransport(opts)
+	} else {
+		transport, err = opts.transport()
+	}
+	if err != nil {
+		return err
+	}
+
+	h.closeIdle()
+
+	h.client.Transport = transport
+	h.client.Timeout = opts.timeout()
+	h.tlsClients = make(map[string]*http.Client)
+	h.options = &opts
+
+	return nil
+}
+
+// closeIdle 关闭 bot 独占的 transport 上的空闲连接（共享的 transport 不处理
+func (h *HttpModule) closeIdle() {
+	if h.options == nil || h.options.Shared {
+		return
+	}
+
+	if t, ok := h.client.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	for _, c := range h.tlsClients {
+		if t, ok := c.Transport.(*http.Transport); ok {
+			t.CloseIdleConnections()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package script
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestHttpConfigure(t *testing.T) {
+	shared1, shared2 := NewHttpModule(), NewHttpModule()
+	assert.Equal(t, shared1.Configure(HttpOptions{MaxIdleConns: 8, Shared: true}), nil)
+	assert.Equal(t, shared2.Configure(HttpOptions{MaxIdleConns: 8, Shared: true}), nil)
+	assert.True(t, shared1.client.Transport == shared2.client.Transport)
+
+	tr := shared1.client.Transport.(*http.Transport)
+	assert.Equal(t, tr.MaxIdleConnsPerHost, defaultSharedIdleConnsPerHost)
+
+	// 默认每个 bot 使用独立的 transport
+	bot1, bot2 := NewHttpModule(), NewHttpModule()
+	assert.Equal(t, bot1.Configure(HttpOptions{}), nil)
+	assert.Equal(t, bot2.Configure(HttpOptions{}), nil)
+	assert.True(t, bot1.client.Transport != bot2.client.Transport)
+
+	tr = bot1.client.Transport.(*http.Transport)
+	assert.False(t, tr.ForceAttemptHTTP2)
+	assert.Equal(t, tr.MaxIdleConnsPerHost, 0)
+
+	assert.NotEqual(t, NewHttpModule().Configure(HttpOptions{SourceIP: "x.x"}), nil)
+
+	opts, err := ParseHttpOptionsXML([]byte(`<http2>true</http2><shared>true</shared><timeout>5</timeout>`))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, opts, HttpOptions{HTTP2: true, Shared: true, Timeout: 5})
+
+	_, err = ParseHttpOptionsXML([]byte(`<source_ip>x.x</source_ip>`))
+	assert.NotEqual(t, err, nil)
+
+	_, err = ParseHttpOptions([]byte(`{"http2":true,"disable_keepalive":true,"timeout":5}`))
+	assert.Equal(t, err, nil)
+}
+
+func TestHttpProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// 通过代理发出的请求 URL 是完整的地址
+		w.Write([]byte("proxy " + req.URL.Host))
+	}))
+	defer proxy.Close()
+
+	httpMod := NewHttpModule()
+	err := httpMod.Configure(HttpOptions{
+		Proxy:            proxy.URL,
+		SourceIP:         "127.0.0.1",
+		DisableKeepAlive: true,
+	})
+	assert.Equal(t, err, nil)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("http", httpMod.Loader)
+
+	err = L.DoString(`
+		local http = require("http")
+
+		local res, err = http.get("http://gobot.test/ping")
+		assert(err == nil, err)
+		assert(res["body"] == "proxy gobot.test", res["body"])
+	`)
+	assert.Equal(t, err, nil)
+}
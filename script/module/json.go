@@ -0,0 +1,290 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// 嵌套的最大深度（避免循环引用的 table 导致栈溢出
+const maxCodecDepth = 128
+
+type codecOptions struct {
+	emptyObject bool       // 空 table 编码为 {}（默认为 []，与 json.lua 保持一致
+	null        lua.LValue // 解码时 null 对应的值（默认为 nil
+}
+
+// jsonNullValue json.null 用于在 table 中表示 null
+// 每个 lua state 有自己的 json.null（json / msgpack 模块共用），脚本修改 json.null 不会影响其他的 bot
+type jsonNullValue struct{}
+
+const jsonNullKey = "_gobot_json_null"
+
+func luaNull(L *lua.LState) *lua.LUserData {
+	if ud, ok := L.G.Registry.RawGetString(jsonNullKey).(*lua.LUserData); ok {
+		return ud
+	}
+
+	ud := L.NewUserData()
+	ud.Value = jsonNullValue{}
+	L.G.Registry.RawSetString(jsonNullKey, ud)
+	return ud
+}
+
+func isArray(tbl *lua.LTable) bool {
+	n := tbl.MaxN()
+	if n == 0 {
+		return false
+	}
+
+	cnt := 0
+	tbl.ForEach(func(_, _ lua.LValue) { cnt++ })
+	return cnt == n
+}
+
+// luaToGo 将 lua 的值转换为用于编码的 go 值（整数会转换为 int64
+func luaToGo(v lua.LValue, opts codecOptions, depth int) (interface{}, error) {
+	if depth > maxCodecDepth {
+		return nil, errors.New("table nested too deep (circular reference?)")
+	}
+
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LNumber:
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("unexpected number %v", f)
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f), nil
+		}
+		return f, nil
+	case *lua.LUserData:
+		if _, ok := v.Value.(jsonNullValue); ok {
+			return nil, nil
+		}
+	case *lua.LTable:
+		if isArray(v) {
+			arr := make([]interface{}, 0, v.MaxN())
+			for i := 1; i <= v.MaxN(); i++ {
+				val, err := luaToGo(v.RawGetInt(i), opts, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, val)
+			}
+			return arr, nil
+		}
+
+		if !opts.emptyObject {
+			empty := true
+			v.ForEach(func(_, _ lua.LValue) { empty = false })
+			if empty {
+				return []interface{}{}, nil
+			}
+		}
+
+		m := make(map[string]interface{})
+		var err error
+		v.ForEach(func(key, val lua.LValue) {
+			if err != nil {
+				return
+			}
+
+			var k string
+			switch key := key.(type) {
+			case lua.LString:
+				k = string(key)
+			case lua.LNumber:
+				k = strconv.FormatFloat(float64(key), 'f', -1, 64)
+			default:
+				err = fmt.Errorf("invalid table key type %v", key.Type())
+				return
+			}
+
+			m[k], err = luaToGo(val, opts, depth+1)
+		})
+		return m, err
+	}
+
+	return nil, fmt.Errorf("unexpected type %v", v.Type())
+}
+
+// goToLua 将解码后的 go 值转换为 lua 的值
+func goToLua(L *lua.LState, v interface{}, opts codecOptions) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		if opts.null != nil {
+			return opts.null
+		}
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case []byte:
+		return lua.LString(v)
+	case json.Number:
+		f, _ := v.Float64()
+		return lua.LNumber(f)
+	case float64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case uint64:
+		return lua.LNumber(v)
+	case int8:
+		return lua.LNumber(v)
+	case int16:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case uint8:
+		return lua.LNumber(v)
+	case uint16:
+		return lua.LNumber(v)
+	case uint32:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case []interface{}:
+		tbl := L.CreateTable(len(v), 0)
+		for k, val := range v {
+			tbl.RawSetInt(k+1, goToLua(L, val, opts))
+		}
+		return tbl
+	case map[string]interface{}:
+		tbl := L.CreateTable(0, len(v))
+		for k, val := range v {
+			tbl.RawSetString(k, goToLua(L, val, opts))
+		}
+		return tbl
+	case map[interface{}]interface{}:
+		tbl := L.CreateTable(0, len(v))
+		for k, val := range v {
+			tbl.RawSet(goToLua(L, k, codecOptions{}), goToLua(L, val, opts))
+		}
+		return tbl
+	}
+
+	return lua.LString(fmt.Sprint(v))
+}
+
+func parseCodecOptions(L *lua.LState, tbl *lua.LTable) codecOptions {
+	opts := codecOptions{}
+	if tbl == nil {
+		return opts
+	}
+
+	opts.emptyObject = lua.LVAsString(tbl.RawGetString("empty_table")) == "object"
+	if lua.LVAsBool(tbl.RawGetString("null")) {
+		opts.null = luaNull(L)
+	}
+
+	return opts
+}
+
+type JSONModule struct {
+}
+
+func (j *JSONModule) Loader(l *lua.LState) int {
+	mod := l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"encode": j.Encode,
+		"decode": j.Decode,
+	})
+	mod.RawSetString("null", luaNull(l))
+	l.Push(mod)
+	return 1
+}
+
+func (j *JSONModule) doEncode(v lua.LValue, opts codecOptions, indent string) (lua.LString, error) {
+	val, err := luaToGo(v, opts, 0)
+	if err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+
+	err = enc.Encode(val)
+	if err != nil {
+		return "", err
+	}
+
+	return lua.LString(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
+
+// encode(val, opts)
+//
+//	opts = {
+//	    empty_table = "array",  -- 空 table 编码为 [] 还是 {}（"array" / "object"
+//	    indent = "  ",          -- 格式化输出
+//	}
+//
+// json.null 编码为 null，无法编码时抛出错误（与 json.lua 一致，可以使用 pcall 捕获
+func (j *JSONModule) Encode(l *lua.LState) int {
+	opts := l.ToTable(2)
+	indent := ""
+	if opts != nil {
+		indent = lua.LVAsString(opts.RawGetString("indent"))
+	}
+
+	v, err := j.doEncode(l.Get(1), parseCodecOptions(l, opts), indent)
+	if err != nil {
+		l.RaiseError("json encode err %v", err.Error())
+		return 0
+	}
+
+	l.Push(v)
+	return 1
+}
+
+func (j *JSONModule) doDecode(l *lua.LState, s string, opts codecOptions) (lua.LValue, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+
+	var val interface{}
+	err := dec.Decode(&val)
+	if err != nil {
+		return lua.LNil, err
+	}
+	if dec.More() {
+		return lua.LNil, errors.New("invalid character after top-level value")
+	}
+
+	return goToLua(l, val, opts), nil
+}
+
+// decode(str, opts)
+//
+//	opts = {
+//	    null = true,    -- null 解码为 json.null（默认解码为 nil，即在 table 中不存在
+//	}
+//
+// 无法解码时抛出错误（与 json.lua 一致
+func (j *JSONModule) Decode(l *lua.LState) int {
+	v, err := j.doDecode(l, l.ToString(1), parseCodecOptions(l, l.ToTable(2)))
+	if err != nil {
+		l.RaiseError("json decode err %v", err.Error())
+		return 0
+	}
+
+	l.Push(v)
+	return 1
+}
@@ -0,0 +1,85 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestJSON(t *testing.T) {
+	jsonMod := JSONModule{}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("json", jsonMod.Loader)
+
+	err := L.DoString(`
+		json = require("json")
+
+		local s = json.encode({id = 1, name = "<bot>", score = 1.5, tags = {"a", "b"}, items = {}})
+		assert(s == '{"id":1,"items":[],"name":"<bot>","score":1.5,"tags":["a","b"]}', s)
+
+		s = json.encode({items = {}}, {empty_table = "object"})
+		assert(s == '{"items":{}}', s)
+
+		s = json.encode({a = json.null, [1] = 2})
+		assert(s == '{"1":2,"a":null}', s)
+
+		local t = {}
+		t.self = t
+		local ok, err = pcall(json.encode, t)
+		assert(not ok and string.find(err, "circular"), "circular")
+
+		local v = json.decode('{"id":1,"arr":[1,null,3],"obj":{"k":null}}')
+		assert(v.id == 1 and v.arr[3] == 3 and v.arr[2] == nil, "decode")
+		assert(v.obj.k == nil, "null as nil")
+
+		v = json.decode('{"k":null,"arr":[null]}', {null = true})
+		assert(v.k == json.null and v.arr[1] == json.null, "null sentinel")
+		assert(json.encode(v) == '{"arr":[null],"k":null}', "null roundtrip")
+
+		ok, err = pcall(json.decode, '{"k":1} x')
+		assert(not ok and string.find(err, "json decode err"), "trailing data")
+	`)
+	assert.Equal(t, err, nil)
+
+	// json.lua 不会覆盖已经预置的 json 模块
+	err = L.DoFile("../json.lua")
+	assert.Equal(t, err, nil)
+	err = L.DoString(`assert(json.null ~= nil, "native json")`)
+	assert.Equal(t, err, nil)
+}
+
+func TestJSONNullPerState(t *testing.T) {
+	newState := func() *lua.LState {
+		L := lua.NewState()
+		L.PreloadModule("json", (&JSONModule{}).Loader)
+		L.PreloadModule("msgpack", (&MsgpackModule{}).Loader)
+		return L
+	}
+
+	L1, L2 := newState(), newState()
+	defer L1.Close()
+	defer L2.Close()
+
+	for _, L := range []*lua.LState{L1, L2} {
+		err := L.DoString(`
+			json = require("json")
+			local msgpack = require("msgpack")
+			assert(json.null == msgpack.null, "shared by json and msgpack")
+		`)
+		assert.Equal(t, err, nil)
+	}
+
+	// 一个 bot 修改 json.null 不会影响其他的 bot
+	err := L1.DoString(`debug.setmetatable(json.null, {__index = function() return "changed" end})`)
+	assert.Equal(t, err, nil)
+	err = L2.DoString(`
+		assert(getmetatable(json.null) == nil, "metatable leaked")
+		assert(json.encode({a = json.null}) == '{"a":null}')
+	`)
+	assert.Equal(t, err, nil)
+	assert.True(t, L1.GetGlobal("json").(*lua.LTable).RawGetString("null") != L2.GetGlobal("json").(*lua.LTable).RawGetString("null"))
+}
@@ -149,7 +149,7 @@ func (m *MgoModule) Loader(L *lua.LState) int {
 }
 
 func (m *MgoModule) conn(L *lua.LState) int {
-	err := m._conn(L.ToString(1), L.ToString(2))
+	err := m._conn(L.ToString(1), L.ToString(2), L.ToTable(3))
 	if err != nil {
 		L.Push(lua.LString(fmt.Sprintf("%s", err)))
 		return 1
@@ -159,7 +159,7 @@ func (m *MgoModule) conn(L *lua.LState) int {
 	return 1
 }
 
-func (m *MgoModule) _conn(db string, url string) error {
+func (m *MgoModule) _conn(db string, url string, opts *lua.LTable) error {
 
 	clientOpt := options.Client()
 	clientOpt.ApplyURI(url)
@@ -167,6 +167,14 @@ func (m *MgoModule) _conn(db string, url string) error {
 	clientOpt.SetMaxPoolSize(1)
 	clientOpt.SetMaxConnIdleTime(60 * time.Second)
 
+	if tlsopts, ok := parseTLSOptions(opts); ok {
+		conf, err := tlsopts.config()
+		if err != nil {
+			return err
+		}
+		clientOpt.SetTLSConfig(conf)
+	}
+
 	client, err := mongo.Connect(context.TODO(), clientOpt)
 	if err != nil {
 		return err
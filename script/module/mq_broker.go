@@ -0,0 +1,336 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/segmentio/kafka-go"
+)
+
+const mqPublishTimeout = time.Second * 5
+
+// deliver 将消息写入订阅者的缓冲区，缓冲区满了则丢弃消息（所有的 broker 一致，不会阻塞 broker 的读取
+func deliver(ch chan mqMessage, msg mqMessage) {
+	select {
+	case ch <- msg:
+	default:
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////// kafka
+
+type kafkaBroker struct {
+	brokers []string
+	writer  *kafka.Writer
+}
+
+func newKafkaBroker(brokers []string) (*kafkaBroker, error) {
+	if len(brokers) == 0 || brokers[0] == "" {
+		return nil, fmt.Errorf("kafka brokers is empty")
+	}
+
+	return &kafkaBroker{
+		brokers: brokers,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: time.Millisecond * 10,
+		},
+	}, nil
+}
+
+func (k *kafkaBroker) publish(msg mqMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mqPublishTimeout)
+	defer cancel()
+
+	kmsg := kafka.Message{
+		Topic: msg.Topic,
+		Key:   []byte(msg.Key),
+		Value: msg.Body,
+	}
+	for hk, hv := range msg.Headers {
+		kmsg.Headers = append(kmsg.Headers, kafka.Header{Key: hk, Value: []byte(hv)})
+	}
+
+	return k.writer.WriteMessages(ctx, kmsg)
+}
+
+func (k *kafkaBroker) subscribe(topic, group string, ch chan mqMessage) (func(), error) {
+	var readers []*kafka.Reader
+
+	if group != "" {
+		readers = append(readers, kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     k.brokers,
+			GroupID:     group,
+			Topic:       topic,
+			StartOffset: kafka.LastOffset,
+			MaxWait:     time.Millisecond * 100,
+		}))
+	} else {
+		var err error
+		readers, err = k.partitionReaders(topic)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, reader := range readers {
+		go k.read(ctx, reader, ch)
+	}
+
+	return func() {
+		cancel()
+		for _, reader := range readers {
+			reader.Close()
+		}
+	}, nil
+}
+
+// partitionReaders 没有指定 group 时直接读取 topic 的所有分区，每个订阅者都能收到全部消息
+// 不会在 broker 上创建 consumer group，从订阅时每个分区的最新位置开始读取（订阅之后发布的消息不会丢失
+func (k *kafkaBroker) partitionReaders(topic string) ([]*kafka.Reader, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mqPublishTimeout)
+	defer cancel()
+
+	conn, err := kafka.DialContext(ctx, "tcp", k.brokers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	readers := []*kafka.Reader{}
+	for _, p := range partitions {
+		offset, err := k.lastOffset(ctx, topic, p.ID)
+		if err != nil {
+			for _, reader := range readers {
+				reader.Close()
+			}
+			return nil, err
+		}
+
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   k.brokers,
+			Topic:     topic,
+			Partition: p.ID,
+			MaxWait:   time.Millisecond * 100,
+		})
+		reader.SetOffset(offset)
+		readers = append(readers, reader)
+	}
+
+	return readers, nil
+}
+
+func (k *kafkaBroker) lastOffset(ctx context.Context, topic string, partition int) (int64, error) {
+	leader, err := kafka.DialLeader(ctx, "tcp", k.brokers[0], topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	defer leader.Close()
+
+	return leader.ReadLastOffset()
+}
+
+func (k *kafkaBroker) read(ctx context.Context, reader *kafka.Reader, ch chan mqMessage) {
+	for {
+		m, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return
+		}
+
+		msg := mqMessage{
+			Topic:   m.Topic,
+			Key:     string(m.Key),
+			Body:    m.Value,
+			Headers: make(map[string]string),
+		}
+		for _, h := range m.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+
+		deliver(ch, msg)
+	}
+}
+
+func (k *kafkaBroker) close() error {
+	return k.writer.Close()
+}
+
+//////////////////////////////////////////////////////////////////////////////// nats
+
+type natsBroker struct {
+	conn *nats.Conn
+}
+
+func newNatsBroker(url string) (*natsBroker, error) {
+	conn, err := nats.Connect(url, nats.Timeout(time.Second*5))
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsBroker{conn: conn}, nil
+}
+
+func (n *natsBroker) publish(msg mqMessage) error {
+	nmsg := nats.NewMsg(msg.Topic)
+	nmsg.Data = msg.Body
+
+	// nats 没有 key 的概念，放在 header 中传递
+	if msg.Key != "" {
+		nmsg.Header.Set("key", msg.Key)
+	}
+	for hk, hv := range msg.Headers {
+		nmsg.Header.Set(hk, hv)
+	}
+	if len(nmsg.Header) == 0 {
+		nmsg.Header = nil
+	}
+
+	return n.conn.PublishMsg(nmsg)
+}
+
+func (n *natsBroker) subscribe(topic, group string, ch chan mqMessage) (func(), error) {
+	handler := func(m *nats.Msg) {
+		msg := mqMessage{
+			Topic:   m.Subject,
+			Body:    m.Data,
+			Headers: make(map[string]string),
+		}
+		for hk := range m.Header {
+			msg.Headers[hk] = m.Header.Get(hk)
+		}
+		msg.Key = msg.Headers["key"]
+
+		deliver(ch, msg)
+	}
+
+	var sub *nats.Subscription
+	var err error
+	if group != "" {
+		sub, err = n.conn.QueueSubscribe(topic, group, handler)
+	} else {
+		sub, err = n.conn.Subscribe(topic, handler)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		sub.Unsubscribe()
+	}, nil
+}
+
+func (n *natsBroker) close() error {
+	n.conn.Close()
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////// amqp
+
+// amqpBroker topic 对应一个 fanout exchange，group 对应绑定在 exchange 上的队列
+type amqpBroker struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+func newAmqpBroker(url string) (*amqpBroker, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &amqpBroker{conn: conn, ch: ch}, nil
+}
+
+func (a *amqpBroker) declare(topic string) error {
+	return a.ch.ExchangeDeclare(topic, "fanout", true, false, false, false, nil)
+}
+
+func (a *amqpBroker) publish(msg mqMessage) error {
+	err := a.declare(msg.Topic)
+	if err != nil {
+		return err
+	}
+
+	headers := amqp.Table{}
+	for hk, hv := range msg.Headers {
+		headers[hk] = hv
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mqPublishTimeout)
+	defer cancel()
+
+	return a.ch.PublishWithContext(ctx, msg.Topic, msg.Key, false, false, amqp.Publishing{
+		Headers: headers,
+		Body:    msg.Body,
+	})
+}
+
+func (a *amqpBroker) subscribe(topic, group string, ch chan mqMessage) (func(), error) {
+	err := a.declare(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var q amqp.Queue
+	if group != "" {
+		q, err = a.ch.QueueDeclare(topic+"."+group, true, false, false, false, nil)
+	} else {
+		q, err = a.ch.QueueDeclare("", false, true, true, false, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.ch.QueueBind(q.Name, "", topic, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := uuid.NewString()
+	deliveries, err := a.ch.Consume(q.Name, consumer, true, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for d := range deliveries {
+			msg := mqMessage{
+				Topic:   topic,
+				Key:     d.RoutingKey,
+				Body:    d.Body,
+				Headers: make(map[string]string),
+			}
+			for hk, hv := range d.Headers {
+				msg.Headers[hk] = fmt.Sprint(hv)
+			}
+
+			deliver(ch, msg)
+		}
+	}()
+
+	return func() {
+		a.ch.Cancel(consumer, false)
+	}, nil
+}
+
+func (a *amqpBroker) close() error {
+	a.ch.Close()
+	return a.conn.Close()
+}
@@ -0,0 +1,124 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestMQMemory(t *testing.T) {
+
+	newState := func() *lua.LState {
+		L := lua.NewState()
+		L.PreloadModule("mq", NewMQModule().Loader)
+		return L
+	}
+
+	producer, consumer1, consumer2 := newState(), newState(), newState()
+	defer producer.Close()
+	defer consumer1.Close()
+	defer consumer2.Close()
+
+	for _, L := range []*lua.LState{consumer1, consumer2} {
+		err := L.DoString(`
+			mq = require("mq")
+			assert(mq.conn("memory", "testmq") == "succ")
+			assert(mq.subscribe("order.done", {group = "workers"}) == "succ")
+			assert(mq.subscribe("broadcast") == "succ")
+		`)
+		assert.Equal(t, err, nil)
+	}
+
+	err := producer.DoString(`
+		local mq = require("mq")
+		assert(mq.conn("memory", "testmq") == "succ")
+
+		for i = 1, 4 do
+			assert(mq.publish("order.done", "order" .. i, {key = "k" .. i, headers = {seq = i}}) == "succ")
+		end
+		assert(mq.publish("broadcast", "hello") == "succ")
+	`)
+	assert.Equal(t, err, nil)
+
+	for _, L := range []*lua.LState{consumer1, consumer2} {
+		err := L.DoString(`
+			-- the group shares the messages
+			local msgs, ret = mq.poll("order.done", 10)
+			assert(ret == "succ", ret)
+			assert(#msgs == 2, "group msgs " .. #msgs)
+			assert(msgs[1].headers.seq ~= nil, "headers")
+
+			-- nothing left, poll must not block
+			msgs = mq.poll("order.done", 10)
+			assert(#msgs == 0, "empty poll")
+
+			-- every subscriber without a group receives the message
+			msgs = mq.poll("broadcast")
+			assert(#msgs == 1 and msgs[1].body == "hello", "broadcast")
+
+			assert(mq.close() == "succ")
+		`)
+		assert.Equal(t, err, nil)
+	}
+}
+
+func TestMQNats(t *testing.T) {
+	s := natsserver.RunRandClientPortServer()
+	defer s.Shutdown()
+
+	mod := NewMQModule()
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("mq", mod.Loader)
+	L.SetGlobal("url", lua.LString(s.ClientURL()))
+
+	err := L.DoString(`
+		mq = require("mq")
+		assert(mq.conn("nats", url) == "succ")
+		assert(mq.subscribe("order.done", {buffer = 2}) == "succ")
+
+		for i = 1, 5 do
+			assert(mq.publish("order.done", "order" .. i, {key = "k" .. i, headers = {seq = i}}) == "succ")
+		end
+	`)
+	assert.Equal(t, err, nil)
+
+	// 缓冲区满了之后的消息被丢弃，和 memory 一致
+	assert.Eventually(t, func() bool { return len(mod.subs["order.done"].ch) == 2 }, time.Second, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 50)
+
+	err = L.DoString(`
+		local msgs, ret = mq.poll("order.done", 10)
+		assert(ret == "succ", ret)
+		assert(#msgs == 2, "buffered msgs " .. #msgs)
+		assert(msgs[1].body == "order1" and msgs[1].key == "k1", "body")
+		assert(msgs[1].headers.seq == "1", "headers")
+
+		assert(mq.close() == "succ")
+	`)
+	assert.Equal(t, err, nil)
+}
+
+func TestMQMemoryOverflow(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("mq", NewMQModule().Loader)
+
+	err := L.DoString(`
+		local mq = require("mq")
+		assert(mq.conn("memory", "overflow") == "succ")
+		assert(mq.subscribe("order.done", {buffer = 2}) == "succ")
+
+		for i = 1, 5 do
+			assert(mq.publish("order.done", "order" .. i) == "succ")
+		end
+
+		local msgs = mq.poll("order.done", 10)
+		assert(#msgs == 2 and msgs[2].body == "order2", "buffered msgs " .. #msgs)
+		assert(mq.close() == "succ")
+	`)
+	assert.Equal(t, err, nil)
+}
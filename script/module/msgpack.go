@@ -0,0 +1,82 @@
+package script
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type MsgpackModule struct {
+}
+
+func (m *MsgpackModule) Loader(l *lua.LState) int {
+	mod := l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"encode": m.Encode,
+		"decode": m.Decode,
+	})
+	mod.RawSetString("null", luaNull(l))
+	l.Push(mod)
+	return 1
+}
+
+func (m *MsgpackModule) doEncode(v lua.LValue, opts codecOptions) (lua.LString, error) {
+	val, err := luaToGo(v, opts, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+
+	err = enc.Encode(val)
+	if err != nil {
+		return "", err
+	}
+
+	return lua.LString(buf.Bytes()), nil
+}
+
+// encode(val, opts) opts 与 json.encode 相同
+func (m *MsgpackModule) Encode(l *lua.LState) int {
+	v, err := m.doEncode(l.Get(1), parseCodecOptions(l, l.ToTable(2)))
+	l.Push(v)
+
+	if err != nil {
+		l.Push(lua.LString(err.Error()))
+	} else {
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
+func (m *MsgpackModule) doDecode(l *lua.LState, s string, opts codecOptions) (lua.LValue, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader([]byte(s)))
+	// key 不是字符串时解码为 map[interface{}]interface{}
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+
+	val, err := dec.DecodeInterface()
+	if err != nil {
+		return lua.LNil, err
+	}
+
+	return goToLua(l, val, opts), nil
+}
+
+// decode(str, opts) opts 与 json.decode 相同
+func (m *MsgpackModule) Decode(l *lua.LState) int {
+	v, err := m.doDecode(l, l.ToString(1), parseCodecOptions(l, l.ToTable(2)))
+	l.Push(v)
+
+	if err != nil {
+		l.Push(lua.LString(err.Error()))
+	} else {
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
@@ -0,0 +1,37 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestMsgpack(t *testing.T) {
+	msgpackMod := MsgpackModule{}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("msgpack", msgpackMod.Loader)
+
+	err := L.DoString(`
+		local msgpack = require("msgpack")
+
+		local s, err = msgpack.encode({id = 1001, name = "bot", rate = 0.5, list = {1, 2, 3}, ok = true})
+		assert(err == nil, err)
+
+		local v, err = msgpack.decode(s)
+		assert(err == nil, err)
+		assert(v.id == 1001 and v.name == "bot" and v.rate == 0.5 and v.ok == true, "decode")
+		assert(#v.list == 3 and v.list[3] == 3, "decode list")
+
+		-- 1001 使用 int16 编码（0xcd / 0xd1 + 2 字节
+		s = msgpack.encode(1001)
+		assert(#s == 3, "int encode " .. #s)
+
+		v, err = msgpack.decode("\193")
+		assert(err ~= nil, "invalid")
+	`)
+	assert.Equal(t, err, nil)
+}
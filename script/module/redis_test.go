@@ -0,0 +1,92 @@
+package script
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestRedisModule(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	redisMod := NewRedisModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("redis", redisMod.Loader)
+	L.SetGlobal("addr", lua.LString(mr.Addr()))
+
+	err := L.DoString(`
+		local redis = require("redis")
+
+		local ret = redis.conn("redis://" .. addr .. "/0")
+		assert(ret == "succ", ret)
+
+		val, ret = redis.set("session", "token", 60)
+		assert(ret == "succ", ret)
+		val, ret = redis.get("session")
+		assert(val == "token", "get " .. tostring(val))
+		val, ret = redis.get("missing")
+		assert(ret == "succ" and val == nil, "missing key")
+		val = redis.ttl("session")
+		assert(val == 60, "ttl " .. val)
+
+		redis.hset("user:1", {name = "joy", lv = 2})
+		val = redis.hgetall("user:1")
+		assert(val.name == "joy" and val.lv == "2", "hgetall")
+
+		redis.zadd("rank", {a = 10, b = 30, c = 20})
+		val = redis.zrevrange("rank", 0, -1, true)
+		assert(val[1].member == "b" and val[1].score == 30, "zrevrange")
+		val = redis.zrange("rank")
+		assert(val[1] == "a" and val[3] == "b", "zrange")
+
+		val = redis.pipeline({ {"incrby", "counter", 2}, {"incrby", "counter", 3}, {"get", "counter"} })
+		assert(val[1] == 2 and val[2] == 5 and val[3] == "5", "pipeline")
+
+		val = redis.eval("return redis.call('get', KEYS[1]) .. ARGV[1]", {"session"}, {"!"})
+		assert(val == "token!", "eval " .. tostring(val))
+
+		val = redis.del("session", "counter")
+		assert(val == 2, "del " .. val)
+
+		assert(redis.disconn() == "succ")
+		val, ret = redis.get("session")
+		assert(val == nil and ret ~= "succ", "get after disconn")
+	`)
+	assert.Equal(t, err, nil)
+}
+
+func TestRedisContext(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("redis", NewRedisModule().Loader)
+	L.SetGlobal("addr", lua.LString(mr.Addr()))
+
+	err := L.DoString(`
+		redis = require("redis")
+		assert(redis.conn(addr) == "succ")
+	`)
+	assert.Equal(t, err, nil)
+
+	// 阻塞的命令在脚本的截止时间被取消（超时节点
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	L.SetContext(ctx)
+
+	begin := time.Now()
+	err = L.DoString(`
+		val, ret = redis["do"]("blpop", "queue", 10)
+	`)
+	L.RemoveContext()
+
+	assert.ErrorContains(t, err, "context deadline exceeded")
+	assert.Less(t, time.Since(begin), time.Second)
+}
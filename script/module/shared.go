@@ -0,0 +1,391 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	ErrSharedQueueEmpty = errors.New("queue empty")
+)
+
+// barrier 人数没有到齐时返回，到齐后触发 shared:name 事件
+const sharedWait = "wait"
+
+// SharedStore batch 内所有 bot 共享的数据（由 Batch 持有，线程安全
+// 不会阻塞调用方，需要等待时通过 shared:key 事件唤醒 bot（WaitForEventNode
+type SharedStore struct {
+	sync.Mutex
+
+	kv       map[string]interface{}
+	queues   map[string][]interface{}
+	poppers  map[string]map[*Events]struct{} // 队列为空时 pop 的 bot，下一次 push 时触发一次事件
+	barriers map[string]map[*Events]struct{} // 已经到达 barrier 的 bot
+	watchers map[string]map[*Events]struct{} // 数据被修改时触发 shared:key 事件
+}
+
+func NewSharedStore() *SharedStore {
+	return &SharedStore{
+		kv:       make(map[string]interface{}),
+		queues:   make(map[string][]interface{}),
+		poppers:  make(map[string]map[*Events]struct{}),
+		barriers: make(map[string]map[*Events]struct{}),
+		watchers: make(map[string]map[*Events]struct{}),
+	}
+}
+
+func addEvents(m map[string]map[*Events]struct{}, key string, e *Events) {
+	if _, ok := m[key]; !ok {
+		m[key] = make(map[*Events]struct{})
+	}
+	m[key][e] = struct{}{}
+}
+
+func rmvEvents(m map[string]map[*Events]struct{}, key string, e *Events) {
+	delete(m[key], e)
+	if len(m[key]) == 0 {
+		delete(m, key)
+	}
+}
+
+// Watch key 对应的值或者队列被修改时触发 e 的 shared:key 事件
+func (s *SharedStore) Watch(key string, e *Events) {
+	s.Lock()
+	defer s.Unlock()
+
+	addEvents(s.watchers, key, e)
+}
+
+// Unwatch 移除 e 的所有监听，并退出正在等待的 pop 和 barrier
+func (s *SharedStore) Unwatch(e *Events) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, m := range []map[string]map[*Events]struct{}{s.watchers, s.poppers, s.barriers} {
+		for key := range m {
+			rmvEvents(m, key, e)
+		}
+	}
+}
+
+// changed 需要在加锁时调用
+func (s *SharedStore) changed(key string) {
+	for e := range s.watchers[key] {
+		e.Signal(EventSharedPrefix + key)
+	}
+}
+
+func (s *SharedStore) Get(key string) interface{} {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.kv[key]
+}
+
+func (s *SharedStore) Set(key string, val interface{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	defer s.changed(key)
+
+	if val == nil {
+		delete(s.kv, key)
+		return
+	}
+	s.kv[key] = val
+}
+
+// Incr 原子的增加 key 对应的数值（key 不存在时从 0 开始
+func (s *SharedStore) Incr(key string, delta interface{}) (interface{}, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var ret interface{}
+	switch old := s.kv[key].(type) {
+	case nil:
+		ret = delta
+	case int64:
+		if d, ok := delta.(int64); ok {
+			ret = old + d
+		} else {
+			ret = float64(old) + delta.(float64)
+		}
+	case float64:
+		if d, ok := delta.(int64); ok {
+			ret = old + float64(d)
+		} else {
+			ret = old + delta.(float64)
+		}
+	default:
+		return nil, fmt.Errorf("value of %v is not a number", key)
+	}
+
+	s.kv[key] = ret
+	s.changed(key)
+	return ret, nil
+}
+
+func (s *SharedStore) Push(queue string, val interface{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.queues[queue] = append(s.queues[queue], val)
+	s.changed(queue)
+	for e := range s.poppers[queue] {
+		e.Signal(EventSharedPrefix + queue)
+	}
+	delete(s.poppers, queue)
+}
+
+// Pop 从队列头部取出数据，队列为空时 e 会在下一次 Push 时收到 shared:queue 事件
+func (s *SharedStore) Pop(queue string, e *Events) (interface{}, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if q := s.queues[queue]; len(q) > 0 {
+		val := q[0]
+		q[0] = nil
+		s.queues[queue] = q[1:]
+		return val, nil
+	}
+
+	if e != nil {
+		addEvents(s.poppers, queue, e)
+	}
+	return nil, ErrSharedQueueEmpty
+}
+
+func (s *SharedStore) Len(queue string) int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.queues[queue])
+}
+
+// Arrive 到达 barrier，第 n 个 bot 到达时返回 true，之前到达的 bot 会收到 shared:name 事件
+// 等待中的 bot 重复到达不会增加计数
+func (s *SharedStore) Arrive(name string, n int, e *Events) (bool, error) {
+	if n <= 0 {
+		return false, fmt.Errorf("invalid barrier size %v", n)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	addEvents(s.barriers, name, e)
+	if len(s.barriers[name]) < n {
+		return false, nil
+	}
+
+	for w := range s.barriers[name] {
+		if w != e {
+			w.Signal(EventSharedPrefix + name)
+		}
+	}
+	delete(s.barriers, name)
+	return true, nil
+}
+
+// Leave 退出 barrier 本轮的计数（等待超时后
+func (s *SharedStore) Leave(name string, e *Events) {
+	s.Lock()
+	defer s.Unlock()
+
+	rmvEvents(s.barriers, name, e)
+}
+
+// SharedModule batch 内 bot 之间共享数据的接口
+//
+//	local shared = require("shared")
+//	shared.set("room", 1001)
+//	shared.incr("login")
+//	shared.push("tokens", token)
+//	local token, err = shared.pop("tokens") -- 队列为空时，下一次 push 触发 shared:tokens 事件
+//	shared.barrier("start", 10)             -- 返回 "wait" 时，人数到齐后触发 shared:start 事件
+//	shared.watch("room")                    -- room 被修改时触发 shared:room 事件
+type SharedModule struct {
+	store  *SharedStore
+	events *Events
+}
+
+func NewSharedModule() *SharedModule {
+	return &SharedModule{}
+}
+
+// SetStore 设置为 batch 的共享数据，为空时使用 bot 私有的数据（调试模式
+func (m *SharedModule) SetStore(s *SharedStore) {
+	if m.store != nil && m.events != nil {
+		m.store.Unwatch(m.events)
+	}
+	m.store = s
+}
+
+// SetEvents 设置 shared.watch 触发的 bot 事件
+func (m *SharedModule) SetEvents(e *Events) {
+	m.events = e
+}
+
+func (m *SharedModule) getStore() *SharedStore {
+	if m.store == nil {
+		m.store = NewSharedStore()
+	}
+	return m.store
+}
+
+func (m *SharedModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"get":  m.get,
+		"set":  m.set,
+		"del":  m.del,
+		"incr": m.incr,
+
+		"push": m.push,
+		"pop":  m.pop,
+		"len":  m.len,
+
+		"barrier": m.barrier,
+		"leave":   m.leave,
+		"watch":   m.watch,
+	})
+	L.Push(mod)
+	return 1
+}
+
+func luaSeconds(v lua.LValue, def float64) time.Duration {
+	if n, ok := v.(lua.LNumber); ok {
+		def = float64(n)
+	}
+	return time.Duration(def * float64(time.Second))
+}
+
+// get(key) 获取共享的值（不存在时返回 nil
+func (m *SharedModule) get(L *lua.LState) int {
+	val := m.getStore().Get(L.CheckString(1))
+	L.Push(goToLua(L, val, codecOptions{}))
+	return 1
+}
+
+// set(key, val) 值会被复制，之后修改 table 不会影响共享的值
+func (m *SharedModule) set(L *lua.LState) int {
+	key := L.CheckString(1)
+
+	val, err := luaToGo(L.Get(2), codecOptions{}, 0)
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	m.getStore().Set(key, val)
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (m *SharedModule) del(L *lua.LState) int {
+	m.getStore().Set(L.CheckString(1), nil)
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// incr(key, delta) 原子的增加，返回增加后的值（delta 默认为 1
+func (m *SharedModule) incr(L *lua.LState) int {
+	key := L.CheckString(1)
+
+	delta, err := luaToGo(L.OptNumber(2, 1), codecOptions{}, 0)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	val, err := m.getStore().Incr(key, delta)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, goToLua(L, val, codecOptions{}))
+}
+
+// push(queue, val) 将数据放入队列尾部
+func (m *SharedModule) push(L *lua.LState) int {
+	queue := L.CheckString(1)
+	if L.Get(2) == lua.LNil {
+		L.Push(lua.LString("can't push nil"))
+		return 1
+	}
+
+	val, err := luaToGo(L.Get(2), codecOptions{}, 0)
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	m.getStore().Push(queue, val)
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// pop(queue) 从队列头部取出数据，队列为空时返回错误，并在下一次 push 时触发 "shared:" .. queue 事件
+func (m *SharedModule) pop(L *lua.LState) int {
+	queue := L.CheckString(1)
+
+	val, err := m.getStore().Pop(queue, m.events)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, goToLua(L, val, codecOptions{}))
+}
+
+func (m *SharedModule) len(L *lua.LState) int {
+	L.Push(lua.LNumber(m.getStore().Len(L.CheckString(1))))
+	return 1
+}
+
+// barrier(name, n) 第 n 个 bot 到达时返回 "succ"，其他的 bot 返回 "wait"，人数到齐后触发 "shared:" .. name 事件
+func (m *SharedModule) barrier(L *lua.LState) int {
+	name := L.CheckString(1)
+	n := L.CheckInt(2)
+
+	if m.events == nil {
+		L.Push(lua.LString("bot without events"))
+		return 1
+	}
+
+	ok, err := m.getStore().Arrive(name, n, m.events)
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	if !ok {
+		L.Push(lua.LString(sharedWait))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// leave(name) 等待 barrier 超时后退出本轮的计数
+func (m *SharedModule) leave(L *lua.LState) int {
+	if m.events != nil {
+		m.getStore().Leave(L.CheckString(1), m.events)
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// watch(key) key 对应的值或者队列被修改时触发 "shared:" .. key 事件（WaitForEventNode
+func (m *SharedModule) watch(L *lua.LState) int {
+	if m.events == nil {
+		L.Push(lua.LString("bot without events"))
+		return 1
+	}
+
+	m.getStore().Watch(L.CheckString(1), m.events)
+	L.Push(lua.LString("succ"))
+	return 1
+}
@@ -0,0 +1,174 @@
+package script
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func newSharedState(store *SharedStore) (*lua.LState, *Events) {
+	e := NewEvents()
+	mod := NewSharedModule()
+	mod.SetStore(store)
+	mod.SetEvents(e)
+
+	L := lua.NewState()
+	L.PreloadModule("shared", mod.Loader)
+	return L, e
+}
+
+func TestSharedModule(t *testing.T) {
+	store := NewSharedStore()
+
+	L, _ := newSharedState(store)
+	defer L.Close()
+
+	err := L.DoString(`
+		local shared = require("shared")
+
+		assert(shared.set("room", {id = 1001, players = {"a"}}) == "succ")
+		local room = shared.get("room")
+		assert(room.id == 1001 and room.players[1] == "a")
+		room.id = 0
+		assert(shared.get("room").id == 1001, "copied")
+
+		assert(shared.incr("cnt") == 1)
+		assert(shared.incr("cnt", 2) == 3)
+		local v, err = shared.incr("room")
+		assert(v == nil and err ~= "succ")
+
+		shared.del("room")
+		assert(shared.get("room") == nil)
+
+		assert(shared.push("q", "t1") == "succ")
+		assert(shared.push("q", {tk = "t2"}) == "succ")
+		assert(shared.push("q", nil) ~= "succ")
+		assert(shared.len("q") == 2)
+
+		local tk, err = shared.pop("q")
+		assert(tk == "t1" and err == "succ")
+		tk = shared.pop("q")
+		assert(tk.tk == "t2")
+
+		tk, err = shared.pop("q")
+		assert(tk == nil and err == "queue empty", err)
+
+		assert(shared.barrier("one", 1) == "succ")
+		assert(shared.barrier("two", 2) == "wait")
+		assert(shared.barrier("two", 2) == "wait")
+		assert(shared.leave("two") == "succ")
+	`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, store.Get("cnt"), int64(3))
+}
+
+func TestSharedConcurrent(t *testing.T) {
+	store := NewSharedStore()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			L, e := newSharedState(store)
+			defer L.Close()
+
+			err := L.DoString(`
+				shared = require("shared")
+				shared.incr("login")
+				ret = shared.barrier("ready", 10)
+			`)
+			if err == nil && L.GetGlobal("ret").String() == "wait" && !waitEvent(e, "shared:ready", time.Second*5) {
+				err = ErrSharedQueueEmpty
+			}
+
+			for err == nil {
+				err = L.DoString(`tk, ret = shared.pop("tokens")`)
+				if L.GetGlobal("ret").String() == "succ" {
+					break
+				}
+				if !waitEvent(e, "shared:tokens", time.Second*5) {
+					err = ErrSharedQueueEmpty
+				}
+			}
+			errs <- err
+		}()
+
+		go func() {
+			defer wg.Done()
+			L, _ := newSharedState(store)
+			defer L.Close()
+
+			errs <- L.DoString(`
+				local shared = require("shared")
+				shared.push("tokens", "tk")
+			`)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Equal(t, err, nil)
+	}
+
+	assert.Equal(t, store.Get("login"), int64(10))
+	assert.Equal(t, store.Len("tokens"), 0)
+}
+
+func TestSharedBarrierEvents(t *testing.T) {
+	store := NewSharedStore()
+	e1, e2 := NewEvents(), NewEvents()
+
+	ok, _ := store.Arrive("b", 2, e1)
+	assert.Equal(t, ok, false)
+
+	// 超时的 bot 不计入本轮
+	store.Leave("b", e1)
+	ok, _ = store.Arrive("b", 2, e2)
+	assert.Equal(t, ok, false)
+	ok, _ = store.Arrive("b", 2, e2)
+	assert.Equal(t, ok, false)
+
+	ok, _ = store.Arrive("b", 2, e1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, e2.Take("shared:b"), true)
+	assert.Equal(t, e1.Take("shared:b"), false)
+
+	// 下一轮重新计数
+	ok, _ = store.Arrive("b", 2, e1)
+	assert.Equal(t, ok, false)
+
+	_, err := store.Arrive("b", 0, e1)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestSharedPopEvents(t *testing.T) {
+	store := NewSharedStore()
+	e := NewEvents()
+
+	_, err := store.Pop("q", e)
+	assert.Equal(t, err, ErrSharedQueueEmpty)
+
+	store.Push("q", "t1")
+	assert.Equal(t, e.Take("shared:q"), true)
+
+	// 只在 pop 失败后触发一次
+	store.Push("q", "t2")
+	assert.Equal(t, e.Take("shared:q"), false)
+
+	val, err := store.Pop("q", e)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "t1")
+
+	store.Unwatch(e)
+	store.Lock()
+	assert.Equal(t, len(store.poppers), 0)
+	store.Unlock()
+}
@@ -0,0 +1,323 @@
+package script
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pojol/gobot/utils"
+	lua "github.com/yuin/gopher-lua"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type SQLModule struct {
+	Driver string
+	db     *gorm.DB
+	tx     *gorm.DB // 当前事务（begin 之后所有的 query/exec 都在事务中执行
+}
+
+func NewSQLModule() *SQLModule {
+	return &SQLModule{}
+}
+
+func (s *SQLModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"open":  s.open,
+		"close": s.close,
+
+		"query": s.query,
+		"exec":  s.exec,
+
+		"begin":       s.begin,
+		"commit":      s.commit,
+		"rollback":    s.rollback,
+		"transaction": s.transaction,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// open(driver, dsn) driver 支持 mysql / sqlite / postgres
+func (s *SQLModule) open(L *lua.LState) int {
+	err := s._open(L.ToString(1), L.ToString(2))
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (s *SQLModule) _open(driver string, dsn string) error {
+	var dialector gorm.Dialector
+
+	switch driver {
+	case "mysql":
+		dialector = mysql.Open(dsn)
+	case "sqlite":
+		dialector = sqlite.Open(dsn)
+	case "postgres":
+		dialector = postgres.Open(dsn)
+	default:
+		return fmt.Errorf("unknow sql driver %v", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return err
+	}
+
+	sqldb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqldb.SetMaxOpenConns(1)
+	sqldb.SetConnMaxIdleTime(60 * time.Second)
+
+	s._close()
+
+	s.db = db
+	s.Driver = driver
+
+	return nil
+}
+
+func (s *SQLModule) _close() error {
+	if s.tx != nil {
+		s.tx.Rollback()
+		s.tx = nil
+	}
+
+	if s.db != nil {
+		sqldb, err := s.db.DB()
+		s.db = nil
+		if err != nil {
+			return err
+		}
+		return sqldb.Close()
+	}
+
+	return nil
+}
+
+// Reset 回滚未提交的事务并关闭连接（lua state 放回池中时调用
+func (s *SQLModule) Reset() {
+	s._close()
+}
+
+func (s *SQLModule) close(L *lua.LState) int {
+	err := s._close()
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (s *SQLModule) conn() (*gorm.DB, error) {
+	if s.tx != nil {
+		return s.tx, nil
+	}
+	if s.db == nil {
+		return nil, errors.New("sql not opened")
+	}
+	return s.db, nil
+}
+
+// sqlArgs 参数既可以是 query(sql, a, b) 也可以是 query(sql, {a, b})
+func sqlArgs(L *lua.LState) []interface{} {
+	if tbl, ok := L.Get(2).(*lua.LTable); ok && L.GetTop() == 2 {
+		args := []interface{}{}
+		for i := 1; i <= tbl.Len(); i++ {
+			args = append(args, luaArg(tbl.RawGetInt(i)))
+		}
+		return args
+	}
+
+	return luaArgs(L, 2)
+}
+
+func scanRows(L *lua.LState, rows *sql.Rows) (*lua.LTable, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	tbl := L.NewTable()
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := L.CreateTable(0, len(cols))
+		for i, col := range cols {
+			switch v := vals[i].(type) {
+			case time.Time:
+				row.RawSetString(col, lua.LString(v.Format(time.RFC3339)))
+			default:
+				row.RawSetString(col, utils.ToLuaValue(L, v))
+			}
+		}
+		tbl.Append(row)
+	}
+
+	return tbl, rows.Err()
+}
+
+// query(sql, args...) 返回 { {col = val, ...}, ... }
+func (s *SQLModule) query(L *lua.LState) int {
+	db, err := s.conn()
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	rows, err := db.Raw(L.CheckString(1), sqlArgs(L)...).Rows()
+	if err != nil {
+		return pushErr(L, err)
+	}
+	defer rows.Close()
+
+	tbl, err := scanRows(L, rows)
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	return pushRet(L, tbl)
+}
+
+// exec(sql, args...) 返回 { rows_affected = n }
+func (s *SQLModule) exec(L *lua.LState) int {
+	db, err := s.conn()
+	if err != nil {
+		return pushErr(L, err)
+	}
+
+	res := db.Exec(L.CheckString(1), sqlArgs(L)...)
+	if res.Error != nil {
+		return pushErr(L, res.Error)
+	}
+
+	tbl := L.NewTable()
+	tbl.RawSetString("rows_affected", lua.LNumber(res.RowsAffected))
+
+	return pushRet(L, tbl)
+}
+
+func (s *SQLModule) _begin() error {
+	if s.db == nil {
+		return errors.New("sql not opened")
+	}
+	if s.tx != nil {
+		return errors.New("transaction already begun")
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	s.tx = tx
+	return nil
+}
+
+func (s *SQLModule) _end(commit bool) error {
+	if s.tx == nil {
+		return errors.New("no transaction")
+	}
+
+	tx := s.tx
+	s.tx = nil
+
+	if commit {
+		return tx.Commit().Error
+	}
+	return tx.Rollback().Error
+}
+
+func (s *SQLModule) begin(L *lua.LState) int {
+	err := s._begin()
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (s *SQLModule) commit(L *lua.LState) int {
+	err := s._end(true)
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (s *SQLModule) rollback(L *lua.LState) int {
+	err := s._end(false)
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// transaction(function() ... end) 函数抛出错误或者返回 false 时回滚，否则提交
+func (s *SQLModule) transaction(L *lua.LState) int {
+	fn := L.CheckFunction(1)
+
+	err := s._begin()
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	err = L.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    1,
+		Protect: true,
+	})
+	if err != nil {
+		s._end(false)
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+	if ret == lua.LFalse {
+		s._end(false)
+		L.Push(lua.LString("rollback"))
+		return 1
+	}
+
+	err = s._end(true)
+	if err != nil {
+		L.Push(lua.LString(fmt.Sprintf("%s", err)))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
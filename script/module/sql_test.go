@@ -0,0 +1,54 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSQLModule(t *testing.T) {
+	sqlMod := NewSQLModule()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	L.PreloadModule("sql", sqlMod.Loader)
+
+	err := L.DoString(`
+		local sql = require("sql")
+
+		local ret = sql.open("sqlite", ":memory:")
+		assert(ret == "succ", ret)
+
+		local res
+		res, ret = sql.exec("create table users (id integer primary key, name text, balance integer)")
+		assert(ret == "succ", ret)
+
+		res, ret = sql.exec("insert into users (name, balance) values (?, ?), (?, ?)", "joy", 100, "pojol", 50)
+		assert(res.rows_affected == 2, ret)
+
+		local rows = sql.query("select * from users where balance >= ? order by id", {50})
+		assert(#rows == 2, "rows " .. #rows)
+		assert(rows[1].name == "joy" and rows[1].balance == 100, "row 1")
+
+		-- rollback when the function fails
+		ret = sql.transaction(function()
+			sql.exec("update users set balance = 0")
+			error("boom")
+		end)
+		assert(ret ~= "succ", "transaction should fail")
+		rows = sql.query("select balance from users where name = ?", "joy")
+		assert(rows[1].balance == 100, "balance rollback")
+
+		-- manual transaction
+		assert(sql.begin() == "succ")
+		sql.exec("delete from users where name = ?", "pojol")
+		assert(sql.commit() == "succ")
+		rows = sql.query("select count(*) as num from users")
+		assert(rows[1].num == 1, "count " .. rows[1].num)
+
+		assert(sql.close() == "succ")
+	`)
+	assert.Equal(t, err, nil)
+}
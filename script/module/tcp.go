@@ -1,54 +1,55 @@
 package script
 
 import (
-	"bytes"
-	"encoding/binary"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"syscall"
+	"time"
 
 	lua "github.com/yuin/gopher-lua"
 )
 
 type TCPModule struct {
-	conn *net.TCPConn
-	fd   int
-	buf  byteQueue
-}
-
-type byteQueue []byte
+	conn    *net.TCPConn
+	tlsconn *tls.Conn
+	file    *os.File // conn.File() 复制的 fd，需要和连接一起关闭
+	fd      int
+	buf     byteQueue
 
-// 入队
-func (q *byteQueue) push(b []byte) {
-	*q = append(*q, b...)
+	events *Events
 }
 
-// 出队
-func (q *byteQueue) pop(maxLen int) ([]byte, bool) {
-	if len(*q) == 0 {
-		return nil, false
-	}
+func NewTCPModule() *TCPModule {
 
-	if maxLen > len(*q) {
-		maxLen = len(*q)
-	}
+	tcpm := &TCPModule{}
 
-	data := (*q)[:maxLen]
-	*q = (*q)[maxLen:]
-	return data, true
+	return tcpm
 }
 
-// 队列当前长度
-func (q *byteQueue) Len() int {
-	return len(*q)
+// SetEvents 连接有数据可以读取时触发 EventTCP 事件
+func (t *TCPModule) SetEvents(e *Events) {
+	t.events = e
 }
 
-func NewTCPModule() *TCPModule {
-
-	tcpm := &TCPModule{}
+// watch 等待连接可读并触发事件（不读取数据，连接关闭时退出
+func (t *TCPModule) watch(conn *net.TCPConn) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
 
-	return tcpm
+	events := t.events
+	first := true
+	rc.Read(func(fd uintptr) bool {
+		if !first {
+			events.Signal(EventTCP)
+		}
+		first = false
+		return false
+	})
 }
 
 func (t *TCPModule) Loader(L *lua.LState) int {
@@ -67,7 +68,7 @@ func (t *TCPModule) Loader(L *lua.LState) int {
 }
 
 func (t *TCPModule) dail(L *lua.LState) int {
-	err := t._dail(L.ToString(1), L.ToString(2))
+	err := t._dail(L.ToString(1), L.ToString(2), L.ToTable(3))
 	if err != nil {
 		L.Push(lua.LString(err.Error()))
 		return 1
@@ -77,7 +78,7 @@ func (t *TCPModule) dail(L *lua.LState) int {
 	return 1
 }
 
-func (t *TCPModule) _dail(host string, port string) error {
+func (t *TCPModule) _dail(host string, port string, options *lua.LTable) error {
 	tcpServer, err := net.ResolveTCPAddr("tcp", host+":"+port)
 	if err != nil {
 		return fmt.Errorf("resolve tcp addr err:%s", err.Error())
@@ -88,24 +89,74 @@ func (t *TCPModule) _dail(host string, port string) error {
 		return fmt.Errorf("dial tcp err:%s", err.Error())
 	}
 
-	f, _ := t.conn.File()
-	t.fd = int(f.Fd())
+	if opts, ok := parseTLSOptions(options); ok {
+		conf, err := opts.config()
+		if err != nil {
+			t.conn.Close()
+			t.conn = nil
+			return err
+		}
+		if conf.ServerName == "" {
+			conf.ServerName = host
+		}
+
+		t.tlsconn = tls.Client(t.conn, conf)
+		t.tlsconn.SetDeadline(time.Now().Add(time.Second * 10))
+		err = t.tlsconn.Handshake()
+		if err != nil {
+			t.conn.Close()
+			t.conn, t.tlsconn = nil, nil
+			return fmt.Errorf("tls handshake err:%s", err.Error())
+		}
+		t.tlsconn.SetDeadline(time.Time{})
+
+		// tls 连接不能直接读 fd，读取时通过 deadline 实现非阻塞（不触发 EventTCP 事件
+		return nil
+	}
+
+	t.file, _ = t.conn.File()
+	t.fd = int(t.file.Fd())
 	syscall.SetNonblock(t.fd, true)
 
+	if t.events != nil {
+		go t.watch(t.conn)
+	}
+
 	return nil
 }
 
-func (t *TCPModule) close(L *lua.LState) int {
+func (t *TCPModule) _close() {
+
+	if t.tlsconn != nil {
+		t.tlsconn.Close()
+		t.tlsconn = nil
+	}
 
 	if t.conn != nil {
 		t.conn.Close()
 		t.conn = nil
 	}
 
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+
+	t.buf = t.buf[:0]
+}
+
+func (t *TCPModule) close(L *lua.LState) int {
+	t._close()
+
 	L.Push(lua.LString("succ"))
 	return 1
 }
 
+// Reset 关闭 bot 的连接（lua state 放回池中时调用，避免被下一个 bot 复用
+func (t *TCPModule) Reset() {
+	t._close()
+}
+
 func (t *TCPModule) write(L *lua.LState) int {
 	if t.conn == nil {
 		L.Push(lua.LString("not connected"))
@@ -113,7 +164,7 @@ func (t *TCPModule) write(L *lua.LState) int {
 	}
 
 	msg := L.ToString(1)
-	_, err := t.conn.Write([]byte(msg))
+	_, err := t._write([]byte(msg))
 	if err != nil {
 		L.Push(lua.LString(err.Error()))
 		return 1
@@ -133,7 +184,7 @@ func (t *TCPModule) _read() error {
 
 	//n, err := t.conn.Read(buf)
 	// 非阻塞读取
-	n, err := syscall.Read(t.fd, buf)
+	n, err := t._recv(buf)
 	if err != nil {
 		return fmt.Errorf("syscall.read fd %v size %v err %v", t.fd, n, err.Error())
 	}
@@ -151,15 +202,26 @@ func (t *TCPModule) _read() error {
 	return nil
 }
 
-func readret(L *lua.LState, ty, custom, id int, body []byte, err string) int {
+func (t *TCPModule) _write(b []byte) (int, error) {
+	if t.tlsconn != nil {
+		return t.tlsconn.Write(b)
+	}
 
-	L.Push(lua.LNumber(ty))
-	L.Push(lua.LNumber(custom))
-	L.Push(lua.LNumber(id))
-	L.Push(lua.LString(body))
-	L.Push(lua.LString(err))
+	return t.conn.Write(b)
+}
 
-	return 5
+// _recv 非阻塞读取，没有数据时返回 syscall.EWOULDBLOCK
+func (t *TCPModule) _recv(buf []byte) (int, error) {
+	if t.tlsconn != nil {
+		t.tlsconn.SetReadDeadline(time.Now().Add(time.Millisecond))
+		n, err := t.tlsconn.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return n, syscall.EWOULDBLOCK
+		}
+		return n, err
+	}
+
+	return syscall.Read(t.fd, buf)
 }
 
 func (t *TCPModule) read_msg(L *lua.LState) int {
@@ -169,37 +231,17 @@ func (t *TCPModule) read_msg(L *lua.LState) int {
 	msgcustom := L.ToInt(3)
 	msgid := L.ToInt(4)
 
-	msgleni := int16(0)
-	msgtyi := int8(0)
-	msgcustomi := int16(0)
-	msgidi := int16(0)
-
-	var msgbody []byte
-
 	err := t._read()
 	if err != nil {
 		return readret(L, 0, 0, 0, []byte{}, err.Error())
 	}
 
-	if t.buf.Len() < msglen+msgty+msgcustom+msgid {
+	ty, custom, id, msgbody, ok := t.buf.decodeFrame(msglen, msgty, msgcustom, msgid)
+	if !ok {
 		return readret(L, 0, 0, 0, []byte{}, "nodata")
 	}
 
-	msglenb, _ := t.buf.pop(msglen)
-	binary.Read(bytes.NewBuffer(msglenb), binary.LittleEndian, &msgleni)
-
-	msgtyb, _ := t.buf.pop(msgty)
-	binary.Read(bytes.NewBuffer(msgtyb), binary.LittleEndian, &msgtyi)
-
-	msgcustomb, _ := t.buf.pop(msgcustom)
-	binary.Read(bytes.NewBuffer(msgcustomb), binary.LittleEndian, &msgcustomi)
-
-	msgidb, _ := t.buf.pop(msgid)
-	binary.Read(bytes.NewBuffer(msgidb), binary.LittleEndian, &msgidi)
-
-	msgbody, _ = t.buf.pop(int(msgleni) - (msgty + msgcustom + msgid))
-
-	return readret(L, int(msgtyi), int(msgcustomi), int(msgidi), msgbody, "")
+	return readret(L, ty, custom, id, msgbody, "")
 }
 
 func (t *TCPModule) write_msg(L *lua.LState) int {
@@ -215,15 +257,7 @@ func (t *TCPModule) write_msg(L *lua.LState) int {
 		return 1
 	}
 
-	buf := bytes.NewBuffer(make([]byte, 0, msglen))
-
-	binary.Write(buf, binary.LittleEndian, uint16(msglen))
-	binary.Write(buf, binary.LittleEndian, uint8(msgty))
-	binary.Write(buf, binary.LittleEndian, uint16(msgcustom))
-	binary.Write(buf, binary.LittleEndian, uint16(msgid))
-	buf.WriteString(msgbody)
-
-	_, err := t.conn.Write(buf.Bytes())
+	_, err := t._write(encodeFrame(msglen, msgty, msgcustom, msgid, msgbody))
 	if err != nil {
 		L.Push(lua.LString(err.Error()))
 		return 1
@@ -243,7 +277,7 @@ func (t *TCPModule) read(L *lua.LState) int {
 
 	buf := make([]byte, 128) //test
 	// 非阻塞读取
-	n, err := syscall.Read(t.fd, buf)
+	n, err := t._recv(buf)
 	// 处理读取结果
 	if err == syscall.EWOULDBLOCK {
 		L.Push(lua.LString("fail"))
@@ -0,0 +1,125 @@
+package script
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// scriptPath 脚本目录，脚本中引用的证书等文件会在这个目录下查找
+var scriptPath = "script/"
+
+// SetScriptPath 设置脚本目录（通常与 factory 的 ScriptPath 保持一致
+func SetScriptPath(path string) {
+	scriptPath = path
+}
+
+// readScriptFile 读取文件，相对路径优先按当前目录查找，找不到再到脚本目录查找
+func readScriptFile(name string) ([]byte, error) {
+	if filepath.IsAbs(name) {
+		return os.ReadFile(name)
+	}
+
+	if _, err := os.Stat(name); err == nil {
+		return os.ReadFile(name)
+	}
+
+	return os.ReadFile(filepath.Join(scriptPath, name))
+}
+
+// loadPEM 证书既可以是脚本目录下的文件名，也可以是直接写在配置（如 global 配置）中的 PEM 文本
+func loadPEM(v string) ([]byte, error) {
+	if strings.Contains(v, "-----BEGIN") {
+		return []byte(v), nil
+	}
+
+	return readScriptFile(v)
+}
+
+type tlsOptions struct {
+	CA                 string
+	Cert               string
+	Key                string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// parseTLSOptions 解析脚本中的 tls 选项
+//
+//	tls = true
+//	tls = { ca = "ca.pem", cert = "client.pem", key = "client.key", server_name = "", insecure_skip_verify = false }
+func parseTLSOptions(options *lua.LTable) (*tlsOptions, bool) {
+	if options == nil {
+		return nil, false
+	}
+
+	switch v := options.RawGetString("tls").(type) {
+	case lua.LBool:
+		if bool(v) {
+			return &tlsOptions{}, true
+		}
+	case *lua.LTable:
+		return &tlsOptions{
+			CA:                 lua.LVAsString(v.RawGetString("ca")),
+			Cert:               lua.LVAsString(v.RawGetString("cert")),
+			Key:                lua.LVAsString(v.RawGetString("key")),
+			ServerName:         lua.LVAsString(v.RawGetString("server_name")),
+			InsecureSkipVerify: lua.LVAsBool(v.RawGetString("insecure_skip_verify")),
+		}, true
+	}
+
+	return nil, false
+}
+
+func (o *tlsOptions) key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%v", o.CA, o.Cert, o.Key, o.ServerName, o.InsecureSkipVerify)
+}
+
+func (o *tlsOptions) config() (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CA != "" {
+		ca, err := loadPEM(o.CA)
+		if err != nil {
+			return nil, fmt.Errorf("load tls ca err : %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("tls ca does not contain any valid certificate")
+		}
+		conf.RootCAs = pool
+	}
+
+	if o.Cert != "" || o.Key != "" {
+		if o.Cert == "" || o.Key == "" {
+			return nil, errors.New("tls cert and key must be set together")
+		}
+
+		certPEM, err := loadPEM(o.Cert)
+		if err != nil {
+			return nil, fmt.Errorf("load tls cert err : %w", err)
+		}
+		keyPEM, err := loadPEM(o.Key)
+		if err != nil {
+			return nil, fmt.Errorf("load tls key err : %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("tls key pair err : %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,88 @@
+package script
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestTLSDial(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("tls"))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	err := os.WriteFile(filepath.Join(dir, "ca.pem"), ca, 0644)
+	assert.Equal(t, err, nil)
+
+	SetScriptPath(dir)
+	defer SetScriptPath("script/")
+
+	// tls echo server (reuse the httptest certificate)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", ts.TLS)
+	assert.Equal(t, err, nil)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	L := lua.NewState()
+	defer L.Close()
+
+	tcpMod := NewTCPModule()
+	httpMod := NewHttpModule()
+	L.PreloadModule("tcpconn", tcpMod.Loader)
+	L.PreloadModule("http", httpMod.Loader)
+	L.SetGlobal("url", lua.LString(ts.URL))
+	L.SetGlobal("port", lua.LString(port))
+	L.SetGlobal("capem", lua.LString(ca))
+
+	err = L.DoString(`
+		local http = require("http")
+		local conn = require("tcpconn")
+
+		local res, err = http.get(url, {tls = {ca = "ca.pem"}})
+		assert(err == nil, err)
+		assert(res["body"] == "tls", "http body " .. res["body"])
+
+		res, err = http.get(url, {})
+		assert(err ~= nil, "unknown authority should fail")
+
+		local ret = conn.dail("127.0.0.1", port, {tls = {ca = capem, server_name = "example.com"}})
+		assert(ret == "succ", ret)
+
+		ret = conn.write("hello")
+		assert(ret == "succ", ret)
+
+		local state, msg
+		for i = 1, 100 do
+			state, msg = conn.read()
+			if state == "succ" then
+				break
+			end
+		end
+		assert(state == "succ", msg)
+		assert(msg == "hello", msg)
+
+		conn.close()
+	`)
+	assert.Equal(t, err, nil)
+}
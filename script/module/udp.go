@@ -0,0 +1,275 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/xtaci/kcp-go/v5"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type UDPModule struct {
+	conn    *net.UDPConn
+	session *kcp.UDPSession
+	buf     byteQueue
+}
+
+func NewUDPModule() *UDPModule {
+
+	udpm := &UDPModule{}
+
+	return udpm
+}
+
+func (u *UDPModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"dail":  u.dail,
+		"close": u.close,
+
+		"write": u.write,
+		"read":  u.read,
+
+		"read_msg":  u.read_msg,
+		"write_msg": u.write_msg,
+	})
+	L.Push(mod)
+	return 1
+}
+
+func (u *UDPModule) dail(L *lua.LState) int {
+	err := u._dail(L.ToString(1), L.ToString(2), L.ToTable(3))
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// parseKCPOptions 解析 kcp 会话参数
+//
+//	kcp = true
+//	kcp = { nodelay = 1, interval = 10, resend = 2, nc = 1, sndwnd = 128, rcvwnd = 128, mtu = 1400 }
+func parseKCPOptions(options *lua.LTable) (*lua.LTable, bool) {
+	if options == nil {
+		return nil, false
+	}
+
+	switch v := options.RawGetString("kcp").(type) {
+	case lua.LBool:
+		if bool(v) {
+			return &lua.LTable{}, true
+		}
+	case *lua.LTable:
+		return v, true
+	}
+
+	return nil, false
+}
+
+func kcpOptInt(opts *lua.LTable, key string, def int) int {
+	if n, ok := opts.RawGetString(key).(lua.LNumber); ok {
+		return int(n)
+	}
+	return def
+}
+
+func (u *UDPModule) _dail(host string, port string, options *lua.LTable) error {
+
+	if opts, ok := parseKCPOptions(options); ok {
+		sess, err := kcp.DialWithOptions(host+":"+port, nil, 0, 0)
+		if err != nil {
+			return fmt.Errorf("dial kcp err:%s", err.Error())
+		}
+
+		// 默认使用极速模式（战斗同步类的流量一般都是这么配置的
+		sess.SetNoDelay(
+			kcpOptInt(opts, "nodelay", 1),
+			kcpOptInt(opts, "interval", 10),
+			kcpOptInt(opts, "resend", 2),
+			kcpOptInt(opts, "nc", 1),
+		)
+		sess.SetWindowSize(kcpOptInt(opts, "sndwnd", 128), kcpOptInt(opts, "rcvwnd", 128))
+		sess.SetMtu(kcpOptInt(opts, "mtu", 1400))
+		sess.SetStreamMode(true)
+
+		u.session = sess
+		return nil
+	}
+
+	udpServer, err := net.ResolveUDPAddr("udp", host+":"+port)
+	if err != nil {
+		return fmt.Errorf("resolve udp addr err:%s", err.Error())
+	}
+
+	u.conn, err = net.DialUDP("udp", nil, udpServer)
+	if err != nil {
+		return fmt.Errorf("dial udp err:%s", err.Error())
+	}
+
+	return nil
+}
+
+func (u *UDPModule) connected() bool {
+	return u.conn != nil || u.session != nil
+}
+
+func (u *UDPModule) _close() {
+
+	if u.session != nil {
+		u.session.Close()
+		u.session = nil
+	}
+
+	if u.conn != nil {
+		u.conn.Close()
+		u.conn = nil
+	}
+
+	u.buf = u.buf[:0]
+}
+
+func (u *UDPModule) close(L *lua.LState) int {
+	u._close()
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+// Reset 关闭 bot 的连接（lua state 放回池中时调用
+func (u *UDPModule) Reset() {
+	u._close()
+}
+
+func (u *UDPModule) _write(b []byte) (int, error) {
+	if u.session != nil {
+		return u.session.Write(b)
+	}
+
+	return u.conn.Write(b)
+}
+
+// _recv 非阻塞读取（udp 模式下每次读取一个完整的数据报
+func (u *UDPModule) _recv(buf []byte) (int, error) {
+	var n int
+	var err error
+
+	if u.session != nil {
+		u.session.SetReadDeadline(time.Now().Add(time.Millisecond))
+		n, err = u.session.Read(buf)
+	} else {
+		u.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+		n, err = u.conn.Read(buf)
+	}
+
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, nil
+	}
+
+	// kcp 的超时错误没有实现 os.ErrDeadlineExceeded
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return n, nil
+	}
+
+	return n, err
+}
+
+func (u *UDPModule) write(L *lua.LState) int {
+	if !u.connected() {
+		L.Push(lua.LString("not connected"))
+		return 1
+	}
+
+	msg := L.ToString(1)
+	_, err := u._write([]byte(msg))
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
+
+func (u *UDPModule) read(L *lua.LState) int {
+
+	if !u.connected() {
+		L.Push(lua.LString("fail"))
+		L.Push(lua.LString("not connected"))
+		return 2
+	}
+
+	buf := make([]byte, 65535)
+	n, err := u._recv(buf)
+	if err != nil {
+		L.Push(lua.LString("fail"))
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	if n == 0 {
+		L.Push(lua.LString("fail"))
+		L.Push(lua.LString("nodata"))
+		return 2
+	}
+
+	L.Push(lua.LString("succ"))
+	L.Push(lua.LString(buf[:n]))
+	return 2
+}
+
+func (u *UDPModule) read_msg(L *lua.LState) int {
+
+	msglen := L.ToInt(1)
+	msgty := L.ToInt(2)
+	msgcustom := L.ToInt(3)
+	msgid := L.ToInt(4)
+
+	if !u.connected() {
+		return readret(L, 0, 0, 0, []byte{}, "not connected")
+	}
+
+	buf := make([]byte, 65535)
+	n, err := u._recv(buf)
+	if err != nil {
+		return readret(L, 0, 0, 0, []byte{}, err.Error())
+	}
+	if n != 0 {
+		u.buf.push(buf[:n])
+	}
+
+	ty, custom, id, msgbody, ok := u.buf.decodeFrame(msglen, msgty, msgcustom, msgid)
+	if !ok {
+		return readret(L, 0, 0, 0, []byte{}, "nodata")
+	}
+
+	return readret(L, ty, custom, id, msgbody, "")
+}
+
+func (u *UDPModule) write_msg(L *lua.LState) int {
+
+	msglen := L.ToInt(1)
+	msgty := L.ToInt(2)
+	msgcustom := L.ToInt(3)
+	msgid := L.ToInt(4)
+	msgbody := L.ToString(5)
+
+	if !u.connected() {
+		L.Push(lua.LString("not connected"))
+		return 1
+	}
+
+	_, err := u._write(encodeFrame(msglen, msgty, msgcustom, msgid, msgbody))
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	L.Push(lua.LString("succ"))
+	return 1
+}
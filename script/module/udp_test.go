@@ -0,0 +1,102 @@
+package script
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtaci/kcp-go/v5"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func udpEchoServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Equal(t, err, nil)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	return port
+}
+
+func kcpEchoServer(t *testing.T) string {
+	ln, err := kcp.ListenWithOptions("127.0.0.1:0", nil, 0, 0)
+	assert.Equal(t, err, nil)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			sess, err := ln.AcceptKCP()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer sess.Close()
+				buf := make([]byte, 4096)
+				for {
+					n, err := sess.Read(buf)
+					if err != nil {
+						return
+					}
+					sess.Write(buf[:n])
+				}
+			}()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func TestUdpMsgPack(t *testing.T) {
+
+	for name, port := range map[string]string{
+		"udp": udpEchoServer(t),
+		"kcp": kcpEchoServer(t),
+	} {
+		L := lua.NewState()
+
+		udpMod := NewUDPModule()
+		L.PreloadModule("udpconn", udpMod.Loader)
+		L.SetGlobal("port", lua.LString(port))
+		L.SetGlobal("usekcp", lua.LBool(name == "kcp"))
+
+		err := L.DoString(`
+			local conn = require("udpconn")
+
+			local ret = conn.dail("127.0.0.1", port, {kcp = usekcp})
+			assert(ret == "succ", ret)
+
+			local body = "battle frame"
+			ret = conn.write_msg(7+#body, 1, 0, 2001, body)
+			assert(ret == "succ", ret)
+
+			local ty, custom, msgid, msgbody, err
+			for i = 1, 500 do
+				ty, custom, msgid, msgbody, err = conn.read_msg(2, 1, 2, 2)
+				if err == "" then
+					break
+				end
+			end
+
+			assert(err == "", err)
+			assert(ty == 1, "ty " .. ty)
+			assert(msgid == 2001, "msgid " .. msgid)
+			assert(msgbody == body, msgbody)
+
+			conn.close()
+		`)
+		assert.Equal(t, err, nil, name)
+		L.Close()
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/pojol/gobot/database"
 	"github.com/pojol/gobot/factory"
+	script "github.com/pojol/gobot/script/module"
 )
 
 type Response struct {
@@ -58,18 +59,24 @@ type ConfigGetSysInfoRes struct {
 	ReportSize   int
 	ChannelSize  int
 	EnqueneDelay int
+	HttpOptions  script.HttpOptions
+	ThinkTime    float64
 }
 
 type ConfigSetSysInfoReq struct {
 	ReportSize   int
 	ChannelSize  int
 	EnqueneDelay int
+	HttpOptions  *script.HttpOptions
+	ThinkTime    *float64 // 等待节点的等待时间倍率（0 为功能测试，1 为压力测试
 }
 
 type ConfigSetSysInfoRes struct {
 	ReportSize   int
 	ChannelSize  int
 	EnqueneDelay int
+	HttpOptions  script.HttpOptions
+	ThinkTime    float64
 }
 
 type SetConfigReq struct {
@@ -91,8 +98,11 @@ type ConfigRemoveReq struct {
 
 // bot.batch
 type BotBatchCreateRequest struct {
-	Name string
-	Num  int
+	Name    string
+	Num     int
+	Dataset string // 数据集名（可选
+	Feed    string // sequential / random / unique / circular
+	Seed    int64  // 随机数种子（可选，使用相同的种子复现随机节点的执行路径
 }
 
 type BotBatchCreateResponse struct {
@@ -148,3 +158,16 @@ type PrefabSetTagsReq struct {
 	Name string   `json:"name"`
 	Tags []string `json:"tags"`
 }
+
+type DatasetInfo struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`
+}
+
+type DatasetListRes struct {
+	Lst []DatasetInfo
+}
+
+type DatasetRmvReq struct {
+	Name string `json:"name"`
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/pojol/gobot/bot/behavior"
 	"github.com/pojol/gobot/database"
 	"github.com/pojol/gobot/factory"
+	script "github.com/pojol/gobot/script/module"
 	"github.com/pojol/gobot/utils"
 )
 
@@ -33,25 +34,28 @@ const (
 	ErrRunningErr
 	ErrUploadConfig
 	ErrGetConfig
+	ErrDatasetInvalid
 )
 
 var errmap map[Err]string = map[Err]string{
-	ErrContentRead:   "failed to read request content",
-	ErrJsonInvalid:   "wrong file format",
-	ErrJsonUnmarshal: "json unmarshal err",
-	ErrWrongInput:    "bad request parameter",
-	ErrPluginLoad:    "failed to plugin load",
-	ErrEnd:           "run to the end",
-	ErrBreak:         "run to the break",
-	ErrCantFindBot:   "can't find bot",
-	ErrCreateBot:     "failed to create bot, the behavior tree file needs to be uploaded to the server before creation",
-	ErrEmptyBatch:    "empty batch info",
+	ErrContentRead:    "failed to read request content",
+	ErrJsonInvalid:    "wrong file format",
+	ErrJsonUnmarshal:  "json unmarshal err",
+	ErrWrongInput:     "bad request parameter",
+	ErrPluginLoad:     "failed to plugin load",
+	ErrEnd:            "run to the end",
+	ErrBreak:          "run to the break",
+	ErrCantFindBot:    "can't find bot",
+	ErrCreateBot:      "failed to create bot, the behavior tree file needs to be uploaded to the server before creation",
+	ErrEmptyBatch:     "empty batch info",
+	ErrDatasetInvalid: "wrong dataset format, expected csv with header or json array",
 }
 
 func FileBlobUpload(ctx echo.Context) error {
 	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
 	res := &Response{}
 	code := Succ
+	var problems []behavior.Problem
 
 	name := ctx.Request().Header.Get("FileName")
 	bts, err := ioutil.ReadAll(ctx.Request().Body)
@@ -67,9 +71,8 @@ func FileBlobUpload(ctx echo.Context) error {
 		goto EXT
 	}
 
-	_, err = behavior.Load(bts, behavior.Step)
-	if err != nil {
-		fmt.Println(err.Error())
+	problems = behavior.Validate(bts, prefabTypes()...)
+	if behavior.HasError(problems) {
 		code = ErrJsonInvalid
 		goto EXT
 	}
@@ -79,6 +82,7 @@ func FileBlobUpload(ctx echo.Context) error {
 EXT:
 	res.Code = int(code)
 	res.Msg = errmap[code]
+	res.Body = problems
 
 	ctx.JSON(http.StatusOK, res)
 	return nil
@@ -91,6 +95,7 @@ func FileTextUpload(ctx echo.Context) error {
 	var upload *utils.UploadFile
 	var fbyte []byte
 	var name string
+	var problems []behavior.Problem
 
 	f, header, err := ctx.Request().FormFile("file")
 	if err != nil {
@@ -107,9 +112,8 @@ func FileTextUpload(ctx echo.Context) error {
 	}
 
 	name = upload.FileName()
-	_, err = behavior.Load(fbyte, behavior.Step)
-	if err != nil {
-		fmt.Println(err.Error())
+	problems = behavior.Validate(fbyte, prefabTypes()...)
+	if behavior.HasError(problems) {
 		code = ErrJsonInvalid
 		goto EXT
 	}
@@ -119,11 +123,24 @@ func FileTextUpload(ctx echo.Context) error {
 EXT:
 	res.Code = int(code)
 	res.Msg = errmap[code]
+	res.Body = problems
 
 	ctx.JSON(http.StatusOK, res)
 	return nil
 }
 
+// prefabTypes 预制节点的类型，作为脚本节点执行
+func prefabTypes() []string {
+	names := []string{}
+
+	tabs, _ := database.GetPrefab().List()
+	for _, v := range tabs {
+		names = append(names, v.Name)
+	}
+
+	return names
+}
+
 func PrefabUpload(ctx echo.Context) error {
 	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
 	res := &Response{
@@ -254,6 +271,106 @@ ext:
 	return nil
 }
 
+func DatasetUpload(ctx echo.Context) error {
+	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
+	res := &Response{
+		Code: int(Succ),
+	}
+
+	name := ctx.Request().Header.Get("FileName")
+	bts, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		res.Code = ErrContentRead
+		goto ext
+	}
+
+	if name == "" {
+		res.Code = ErrWrongInput
+		res.Msg = errmap[ErrWrongInput]
+		goto ext
+	}
+
+	_, err = script.ParseDataset(bts)
+	if err != nil {
+		fmt.Println(err.Error())
+		res.Code = ErrDatasetInvalid
+		res.Msg = err.Error()
+		goto ext
+	}
+
+	database.GetDataset().Upset(name, bts)
+
+ext:
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
+
+func DatasetList(ctx echo.Context) error {
+	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
+	res := &Response{
+		Code: int(Succ),
+	}
+	body := DatasetListRes{}
+
+	tabs, _ := database.GetDataset().List()
+	for _, v := range tabs {
+		rows, _ := script.ParseDataset(v.Data)
+		body.Lst = append(body.Lst, DatasetInfo{
+			Name: v.Name,
+			Rows: len(rows),
+		})
+	}
+
+	res.Body = body
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
+
+func DatasetGetInfo(ctx echo.Context) error {
+	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
+
+	name := ctx.Request().Header.Get("FileName")
+
+	tab, _ := database.GetDataset().Find(name)
+
+	ctx.Blob(http.StatusOK, "text/plain;charset=utf-8", tab.Data)
+	return nil
+}
+
+func DatasetRmv(ctx echo.Context) error {
+	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
+	res := &Response{
+		Code: int(Succ),
+	}
+	req := &DatasetRmvReq{}
+
+	bts, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		fmt.Println(err.Error())
+		res.Code = ErrContentRead
+		goto ext
+	}
+
+	err = json.Unmarshal(bts, &req)
+	if err != nil {
+		res.Code = ErrJsonInvalid
+		fmt.Println(err.Error())
+		goto ext
+	}
+
+	if req.Name != "" {
+		err = database.GetDataset().Rmv(req.Name)
+		if err != nil {
+			res.Code = int(Fail)
+			res.Msg = err.Error()
+		}
+	}
+
+ext:
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
+
 func FileRemove(ctx echo.Context) error {
 	ctx.Response().Header().Set("Access-Control-Allow-Origin", "*")
 	code := Succ
@@ -404,6 +521,8 @@ func ConfigGetSysInfo(ctx echo.Context) error {
 	body.ChannelSize = conf.ChannelSize
 	body.ReportSize = conf.ReportSize
 	body.EnqueneDelay = conf.EnqueneDelay
+	body.HttpOptions, _ = script.ParseHttpOptions(conf.HttpOptions)
+	body.ThinkTime = conf.ThinkTime
 
 ext:
 	res.Body = body
@@ -420,6 +539,7 @@ func ConfigSetSysInfo(ctx echo.Context) error {
 	req := &ConfigSetSysInfoReq{}
 	conf := database.GetConfig()
 	var newtab database.ConfTable
+	var httpopts []byte
 
 	bts, err := ioutil.ReadAll(ctx.Request().Body)
 	if err != nil {
@@ -442,6 +562,30 @@ func ConfigSetSysInfo(ctx echo.Context) error {
 	if req.EnqueneDelay != 0 {
 		conf.UpdateEnqueneDelay(req.EnqueneDelay)
 	}
+	if req.HttpOptions != nil {
+		httpopts, _ = json.Marshal(req.HttpOptions)
+		_, err = script.ParseHttpOptions(httpopts)
+		if err != nil {
+			res.Code = int(ErrWrongInput)
+			res.Msg = fmt.Sprintf("parse http options err %v", err)
+			goto EXT
+		}
+
+		err = conf.UpdateHttpOptions(httpopts)
+		if err != nil {
+			res.Code = int(ErrWrongInput)
+			res.Msg = err.Error()
+			goto EXT
+		}
+	}
+	if req.ThinkTime != nil {
+		err = conf.UpdateThinkTime(*req.ThinkTime)
+		if err != nil {
+			res.Code = int(ErrWrongInput)
+			res.Msg = err.Error()
+			goto EXT
+		}
+	}
 
 	newtab, err = conf.Get()
 	if err != nil {
@@ -453,6 +597,8 @@ func ConfigSetSysInfo(ctx echo.Context) error {
 	body.ReportSize = newtab.ReportSize
 	body.ChannelSize = newtab.ChannelSize
 	body.EnqueneDelay = newtab.EnqueneDelay
+	body.HttpOptions, _ = script.ParseHttpOptions(newtab.HttpOptions)
+	body.ThinkTime = newtab.ThinkTime
 
 EXT:
 	res.Body = body
@@ -586,7 +732,11 @@ func BotRun(ctx echo.Context) error {
 		goto EXT
 	}
 	b = bot.NewWithBehaviorTree("script/", tree, req.Name, "", 1, string(info.File))
+	if cfg, err := database.GetConfig().Get(); err == nil {
+		b.SetThinkTime(cfg.ThinkTime)
+	}
 	err = b.RunByBlock()
+	b.Close()
 	if err != nil {
 		code = ErrRunningErr
 		errmap[code] = err.Error()
@@ -628,14 +778,23 @@ func BotCreateBatch(ctx echo.Context) error {
 		goto EXT
 	}
 
-	err = factory.Global.AddBatch(req.Name, 0, int32(req.Num))
+	err = factory.Global.AddTask(factory.TaskInfo{
+		Name:    req.Name,
+		Num:     int32(req.Num),
+		Dataset: req.Dataset,
+		Feed:    req.Feed,
+		Seed:    req.Seed,
+	})
 	if err != nil {
+		code = ErrCreateBot
 		res.Msg = err.Error()
 	}
 
 EXT:
 	res.Code = int(code)
-	res.Msg = errmap[code]
+	if res.Msg == "" {
+		res.Msg = errmap[code]
+	}
 
 	ctx.JSON(http.StatusOK, res)
 	return nil
@@ -775,6 +934,11 @@ func Route(e *echo.Echo) {
 	e.POST("/prefab.setTags", PrefabSetTags)
 	e.POST("/prefab.upload", PrefabUpload)
 
+	e.POST("/dataset.upload", DatasetUpload) // 上传 csv / json 数据集
+	e.POST("/dataset.list", DatasetList)
+	e.POST("/dataset.get", DatasetGetInfo)
+	e.POST("/dataset.rmv", DatasetRmv)
+
 	e.POST("/bot.run", BotRun)
 	e.POST("/bot.batch", BotCreateBatch) // 创建一批bot
 	e.POST("/bot.list", BotList)
@@ -104,3 +104,81 @@ func ToGoValue(lv lua.LValue, opt *Option) interface{} {
 		return v
 	}
 }
+
+// Map2Table converts the given map to a lua table (the reverse of Table2Map).
+func Map2Table(L *lua.LState, m map[string]interface{}) *lua.LTable {
+	return ToLuaValue(L, m).(*lua.LTable)
+}
+
+// ToLuaValue converts the given Go object to a LValue.
+func ToLuaValue(L *lua.LState, v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case lua.LValue:
+		return v
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case []byte:
+		return lua.LString(v)
+	case int:
+		return lua.LNumber(v)
+	case int8:
+		return lua.LNumber(v)
+	case int16:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case uint:
+		return lua.LNumber(v)
+	case uint8:
+		return lua.LNumber(v)
+	case uint16:
+		return lua.LNumber(v)
+	case uint32:
+		return lua.LNumber(v)
+	case uint64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case []string:
+		tbl := L.CreateTable(len(v), 0)
+		for _, item := range v {
+			tbl.Append(lua.LString(item))
+		}
+		return tbl
+	case []interface{}:
+		tbl := L.CreateTable(len(v), 0)
+		for i, item := range v {
+			// RawSetInt keeps the index of nil elements
+			tbl.RawSetInt(i+1, ToLuaValue(L, item))
+		}
+		return tbl
+	case map[string]interface{}:
+		tbl := L.CreateTable(0, len(v))
+		for key, item := range v {
+			tbl.RawSetString(key, ToLuaValue(L, item))
+		}
+		return tbl
+	case map[string]string:
+		tbl := L.CreateTable(0, len(v))
+		for key, item := range v {
+			tbl.RawSetString(key, lua.LString(item))
+		}
+		return tbl
+	case map[interface{}]interface{}:
+		tbl := L.CreateTable(0, len(v))
+		for key, item := range v {
+			tbl.RawSet(ToLuaValue(L, key), ToLuaValue(L, item))
+		}
+		return tbl
+	default:
+		return lua.LString(fmt.Sprint(v))
+	}
+}